@@ -0,0 +1,188 @@
+// Package ai 用expectimax搜索为棋盘选择移动方向，不依赖ebiten
+package ai
+
+import (
+	"gameTest/engine"
+	"math"
+	"time"
+)
+
+const (
+	DefaultDepth  = 3                     //默认的搜索深度
+	DefaultBudget = 50 * time.Millisecond //默认的时间预算
+
+	minProbability = 1e-4 //概率小于这个值的分支不再往下搜索
+	lossScore      = -1e6 //没有可以移动的方向时的分数
+)
+
+// Options 搜索的设置
+type Options struct {
+	Depth  int           //最多搜索几步移动 0为DefaultDepth
+	Budget time.Duration //时间预算 超时后使用已经搜完的最深一层的结果 0为不限制
+}
+
+// withDefaults 没有设置的项使用默认值
+func (o Options) withDefaults() Options {
+	if o.Depth <= 0 {
+		o.Depth = DefaultDepth
+	}
+	return o
+}
+
+// Choice 一个方向的评估结果
+type Choice struct {
+	Dir   engine.Dir
+	Valid bool    //这个方向能否移动
+	Score float64 //期望的分数 越大越好
+}
+
+// Evaluate 评估四个方向 按engine.Dirs的顺序返回
+// 从深度1开始逐层加深，超过时间预算时返回已经搜完的最深一层的结果
+func Evaluate(s engine.State, opts Options) [4]Choice {
+	opts = opts.withDefaults()
+	sr := &searcher{}
+	if 0 < opts.Budget {
+		sr.deadline = time.Now().Add(opts.Budget)
+	}
+	evaluate := sr.evaluate
+	//4x4的经典棋盘用Bitboard搜索
+	if b, ok := engine.NewBitboard(s); ok {
+		bs := newBitboardSearcher(sr)
+		evaluate = func(_ engine.State, depth int) ([4]Choice, bool) {
+			return bs.evaluate(b, depth)
+		}
+	}
+	var best [4]Choice
+	for depth := 1; depth <= opts.Depth; depth++ {
+		choices, ok := evaluate(s, depth)
+		if !ok {
+			break
+		}
+		best = choices
+	}
+	return best
+}
+
+// BestMove 选择期望分数最高的方向 没有可以移动的方向时返回false
+func BestMove(s engine.State, opts Options) (engine.Dir, bool) {
+	return Best(Evaluate(s, opts))
+}
+
+// Best 从评估结果中选出分数最高的方向
+func Best(choices [4]Choice) (engine.Dir, bool) {
+	var best *Choice
+	for i := range choices {
+		c := &choices[i]
+		if !c.Valid {
+			continue
+		}
+		if best == nil || best.Score < c.Score {
+			best = c
+		}
+	}
+	if best == nil {
+		return 0, false
+	}
+	return best.Dir, true
+}
+
+// PlayGame 用AI从layout开局一直玩到不能移动或者走完maxMoves步 maxMoves为0时不限制
+// 返回最后的棋盘和走了几步
+func PlayGame(layout engine.State, r engine.Rand, opts Options, maxMoves int) (engine.State, int, error) {
+	s, err := engine.Start(layout, r)
+	if err != nil {
+		return s, 0, err
+	}
+	moves := 0
+	for maxMoves <= 0 || moves < maxMoves {
+		dir, ok := BestMove(s, opts)
+		if !ok {
+			break
+		}
+		s, _, _ = engine.Step(s, dir, r)
+		moves++
+	}
+	return s, moves, nil
+}
+
+// searcher 一次搜索的状态
+type searcher struct {
+	deadline time.Time //超时的时间 零值为不限制
+	aborted  bool      //是否已经超时
+	nodes    int       //搜索过的节点数
+}
+
+// timeout 是否已经超时 每隔一些节点检查一次时间
+func (sr *searcher) timeout() bool {
+	if sr.aborted {
+		return true
+	}
+	sr.nodes++
+	if !sr.deadline.IsZero() && sr.nodes%256 == 0 && time.Now().After(sr.deadline) {
+		sr.aborted = true
+	}
+	return sr.aborted
+}
+
+// evaluate 用固定的深度评估四个方向 超时时返回false
+func (sr *searcher) evaluate(s engine.State, depth int) ([4]Choice, bool) {
+	var choices [4]Choice
+	for i, d := range engine.Dirs {
+		choices[i].Dir = d
+		next, result := s.Move(d)
+		if !result.Moved {
+			continue
+		}
+		choices[i].Valid = true
+		choices[i].Score = sr.chance(next, depth-1, 1)
+		if sr.aborted {
+			return choices, false
+		}
+	}
+	return choices, true
+}
+
+// max 玩家选择最好的方向
+func (sr *searcher) max(s engine.State, depth int, prob float64) float64 {
+	if sr.timeout() {
+		return 0
+	}
+	best := math.Inf(-1)
+	for _, d := range engine.Dirs {
+		next, result := s.Move(d)
+		if !result.Moved {
+			continue
+		}
+		if v := sr.chance(next, depth-1, prob); best < v {
+			best = v
+		}
+	}
+	if math.IsInf(best, -1) {
+		return lossScore
+	}
+	return best
+}
+
+// chance 随机在空位置增加格子 按规则的权重求期望
+func (sr *searcher) chance(s engine.State, depth int, prob float64) float64 {
+	if depth <= 0 || prob < minProbability {
+		return Heuristic(s)
+	}
+	cells := s.EmptyCells()
+	if len(cells) == 0 {
+		return Heuristic(s)
+	}
+	weights := s.Rule().SpawnWeights()
+	total := 0
+	for _, w := range weights {
+		total += w.Weight
+	}
+	sum := 0.0
+	for _, c := range cells {
+		for _, w := range weights {
+			p := float64(w.Weight) / float64(total) / float64(len(cells))
+			sum += p * sr.max(s.Set(c.X, c.Y, w.Value), depth, prob*p)
+		}
+	}
+	return sum
+}
@@ -0,0 +1,175 @@
+package ai
+
+import (
+	"gameTest/engine"
+	"math"
+	"testing"
+)
+
+// grid 按行列出的经典棋盘 0为空位置
+func grid(rows ...[]int) engine.State {
+	s := engine.New(len(rows[0]), len(rows))
+	for y, row := range rows {
+		for x, v := range row {
+			if v != 0 {
+				s = s.Set(x, y, v)
+			}
+		}
+	}
+	return s
+}
+
+func TestEvaluate(t *testing.T) {
+	tests := []struct {
+		name  string
+		state engine.State
+		valid [4]bool //按engine.Dirs的顺序
+	}{
+		{
+			name: "no moves",
+			state: grid(
+				[]int{2, 4, 2, 4},
+				[]int{4, 2, 4, 2},
+				[]int{2, 4, 2, 4},
+				[]int{4, 2, 4, 2},
+			),
+		},
+		{
+			name: "corner hole",
+			state: grid(
+				[]int{0, 4, 2, 4},
+				[]int{4, 2, 4, 2},
+				[]int{2, 4, 2, 4},
+				[]int{4, 2, 4, 2},
+			),
+			valid: [4]bool{true, false, false, true},
+		},
+		{
+			name: "top row pair",
+			state: grid(
+				[]int{2, 2, 0, 0},
+				[]int{0, 0, 0, 0},
+				[]int{0, 0, 0, 0},
+				[]int{0, 0, 0, 0},
+			),
+			valid: [4]bool{false, true, true, true},
+		},
+		{
+			name: "walls",
+			state: grid(
+				[]int{2, 0, 0},
+				[]int{0, 0, 0},
+				[]int{0, 0, 2},
+			).Set(1, 1, engine.Wall),
+			valid: [4]bool{true, true, true, true},
+		},
+		{
+			name: "fibonacci",
+			state: grid(
+				[]int{1, 2, 0},
+				[]int{0, 0, 0},
+				[]int{0, 0, 0},
+			).WithRule(engine.Fibonacci),
+			valid: [4]bool{false, true, true, true},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			choices := Evaluate(tt.state, Options{Depth: 2})
+			movable := false
+			for i, c := range choices {
+				if c.Dir != engine.Dirs[i] {
+					t.Errorf("choice %d dir = %v, want %v", i, c.Dir, engine.Dirs[i])
+				}
+				if c.Valid != tt.valid[i] {
+					t.Errorf("%v valid = %v, want %v", c.Dir, c.Valid, tt.valid[i])
+				}
+				movable = movable || c.Valid
+			}
+			dir, ok := BestMove(tt.state, Options{Depth: 2})
+			if ok != movable {
+				t.Fatalf("BestMove ok = %v, want %v", ok, movable)
+			}
+			if ok && !tt.valid[dir] {
+				t.Errorf("BestMove = %v, which cannot move", dir)
+			}
+		})
+	}
+}
+
+func TestPlayGame(t *testing.T) {
+	layout := engine.New(4, 4)
+	opts := Options{Depth: 1}
+	a, n, err := PlayGame(layout, engine.NewSource(1), opts, 30)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if n != 30 {
+		t.Errorf("moves = %d, want 30", n)
+	}
+	//同样的种子得到同样的棋盘
+	b, _, err := PlayGame(layout, engine.NewSource(1), opts, 30)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if engine.FormatLayout(a) != engine.FormatLayout(b) || a.Score() != b.Score() {
+		t.Errorf("same seed gave different games")
+	}
+	for y := 0; y < 4; y++ {
+		for x := 0; x < 4; x++ {
+			if a.At(x, y) != b.At(x, y) {
+				t.Fatalf("same seed gave different tiles at (%d,%d)", x, y)
+			}
+		}
+	}
+	//2x2的棋盘很快就走不动了
+	s, _, err := PlayGame(engine.New(2, 2), engine.NewSource(2), opts, 0)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if s.CanMove() {
+		t.Errorf("PlayGame stopped while the board can still move")
+	}
+}
+
+func TestBitboardMatchesGeneric(t *testing.T) {
+	//用AI走出一些不同阶段的棋盘
+	r := engine.NewSource(7)
+	s, err := engine.Start(engine.New(4, 4), r)
+	if err != nil {
+		t.Fatal(err)
+	}
+	for i := 0; i < 60 && s.CanMove(); i++ {
+		if i%10 == 0 {
+			compareSearchers(t, s)
+		}
+		dir, _ := BestMove(s, Options{Depth: 1})
+		s, _, _ = engine.Step(s, dir, r)
+	}
+}
+
+// compareSearchers 比较两种搜索对s的评估
+func compareSearchers(t *testing.T, s engine.State) {
+	t.Helper()
+	b, ok := engine.NewBitboard(s)
+	if !ok {
+		t.Fatalf("NewBitboard failed for\n%v", s)
+	}
+	if h, bh := Heuristic(s), bitboardHeuristic(b); !closeTo(h, bh) {
+		t.Errorf("Heuristic = %v, bitboardHeuristic = %v", h, bh)
+	}
+	for depth := 1; depth <= 2; depth++ {
+		generic, _ := (&searcher{}).evaluate(s, depth)
+		bits, _ := newBitboardSearcher(&searcher{}).evaluate(b, depth)
+		for i := range generic {
+			if generic[i].Valid != bits[i].Valid || !closeTo(generic[i].Score, bits[i].Score) {
+				t.Errorf("depth %d %v: generic %+v, bitboard %+v", depth, generic[i].Dir, generic[i], bits[i])
+			}
+		}
+	}
+}
+
+// closeTo 两个分数是否只差浮点误差
+func closeTo(a, b float64) bool {
+	return math.Abs(a-b) <= 1e-9*math.Max(1, math.Abs(a))
+}
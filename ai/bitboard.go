@@ -0,0 +1,131 @@
+package ai
+
+import (
+	"gameTest/engine"
+	"math"
+)
+
+// lineHeuristic 每一行的单调性和平滑度加权后的分数 行和列都用这个表
+var lineHeuristic [1 << 16]float64
+
+// 初始化每一行的分数
+func init() {
+	line := make([]int, 4)
+	for row := 0; row < 1<<16; row++ {
+		for i := range line {
+			line[i] = row >> (4 * i) & 0xf
+		}
+		mono, smooth := lineScore(line)
+		lineHeuristic[row] = monoWeight*mono + smoothWeight*smooth
+	}
+}
+
+// bitboardHeuristic 和Heuristic一样评估4x4的经典棋盘
+// 经典规则中格子的等级就是Bitboard中保存的指数
+func bitboardHeuristic(b engine.Bitboard) float64 {
+	var ranks [16]int
+	empty := 0
+	score := 0.0
+	t := b.Transpose()
+	for y := 0; y < 4; y++ {
+		row := b.Row(y)
+		score += lineHeuristic[row] + lineHeuristic[t.Row(y)]
+		for x := 0; x < 4; x++ {
+			r := int(row>>(4*x)) & 0xf
+			if r == 0 {
+				empty++
+			}
+			ranks[x+y*4] = r
+		}
+	}
+	return score + emptyWeight*float64(empty) + cornerWeight*cornerScore(ranks[:], 4, 4)
+}
+
+// bitboardSpawn 新格子的值和概率
+type bitboardSpawn struct {
+	value int
+	prob  float64
+}
+
+// bitboardSearcher 用Bitboard搜索4x4的经典棋盘 和searcher的结果一样但是快很多
+type bitboardSearcher struct {
+	*searcher
+	spawns []bitboardSpawn
+}
+
+// newBitboardSearcher 按经典规则的权重初始化
+func newBitboardSearcher(sr *searcher) *bitboardSearcher {
+	weights := engine.Classic.SpawnWeights()
+	total := 0
+	for _, w := range weights {
+		total += w.Weight
+	}
+	bs := &bitboardSearcher{searcher: sr}
+	for _, w := range weights {
+		bs.spawns = append(bs.spawns, bitboardSpawn{value: w.Value, prob: float64(w.Weight) / float64(total)})
+	}
+	return bs
+}
+
+// evaluate 用固定的深度评估四个方向 超时时返回false
+func (bs *bitboardSearcher) evaluate(b engine.Bitboard, depth int) ([4]Choice, bool) {
+	var choices [4]Choice
+	for i, d := range engine.Dirs {
+		choices[i].Dir = d
+		next, _, moved := b.Move(d)
+		if !moved {
+			continue
+		}
+		choices[i].Valid = true
+		choices[i].Score = bs.chance(next, depth-1, 1)
+		if bs.aborted {
+			return choices, false
+		}
+	}
+	return choices, true
+}
+
+// max 玩家选择最好的方向
+func (bs *bitboardSearcher) max(b engine.Bitboard, depth int, prob float64) float64 {
+	if bs.timeout() {
+		return 0
+	}
+	best := math.Inf(-1)
+	for _, d := range engine.Dirs {
+		next, _, moved := b.Move(d)
+		if !moved {
+			continue
+		}
+		if v := bs.chance(next, depth-1, prob); best < v {
+			best = v
+		}
+	}
+	if math.IsInf(best, -1) {
+		return lossScore
+	}
+	return best
+}
+
+// chance 随机在空位置增加格子 按规则的权重求期望
+func (bs *bitboardSearcher) chance(b engine.Bitboard, depth int, prob float64) float64 {
+	if depth <= 0 || prob < minProbability {
+		return bitboardHeuristic(b)
+	}
+	n := b.EmptyCount()
+	if n == 0 {
+		return bitboardHeuristic(b)
+	}
+	sum := 0.0
+	for y := 0; y < 4; y++ {
+		for x := 0; x < 4; x++ {
+			if b.At(x, y) != 0 {
+				continue
+			}
+			for _, sp := range bs.spawns {
+				p := sp.prob / float64(n)
+				sum += p * bs.max(b.Set(x, y, sp.value), depth, prob*p)
+			}
+		}
+	}
+	return sum
+}
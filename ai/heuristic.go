@@ -0,0 +1,119 @@
+package ai
+
+import (
+	"gameTest/engine"
+)
+
+// 启发函数各项的权重
+const (
+	emptyWeight  = 2.7
+	monoWeight   = 1.0
+	smoothWeight = 0.1
+	cornerWeight = 1.0
+)
+
+// Heuristic 评估棋盘的好坏 越大越好
+// 使用值在规则数列中的位置计算，空位置多、单调、相邻格子接近、大的格子在角落的棋盘分数高
+func Heuristic(s engine.State) float64 {
+	w, h := s.Size()
+	rule := s.Rule()
+	//每个位置的等级 空位置为0 墙为-1
+	ranks := make([]int, w*h)
+	empty := 0
+	for y := 0; y < h; y++ {
+		for x := 0; x < w; x++ {
+			v := s.At(x, y)
+			switch {
+			case v == engine.Wall:
+				ranks[x+y*w] = -1
+			case v == 0:
+				empty++
+			default:
+				ranks[x+y*w] = rule.Rank(v)
+			}
+		}
+	}
+	rank := func(x, y int) int {
+		return ranks[x+y*w]
+	}
+
+	mono, smooth := 0.0, 0.0
+	//每一行和每一列
+	line := make([]int, w)
+	for y := 0; y < h; y++ {
+		for x := 0; x < w; x++ {
+			line[x] = rank(x, y)
+		}
+		m, sm := lineScore(line)
+		mono += m
+		smooth += sm
+	}
+	line = make([]int, h)
+	for x := 0; x < w; x++ {
+		for y := 0; y < h; y++ {
+			line[y] = rank(x, y)
+		}
+		m, sm := lineScore(line)
+		mono += m
+		smooth += sm
+	}
+
+	return emptyWeight*float64(empty) + monoWeight*mono + smoothWeight*smooth + cornerWeight*cornerScore(ranks, w, h)
+}
+
+// lineScore 一行或者一列的单调性和平滑度 line中为每个位置的等级
+func lineScore(line []int) (mono, smooth float64) {
+	inc, dec := 0, 0
+	for i := 0; i+1 < len(line); i++ {
+		a, b := line[i], line[i+1]
+		//墙两边不比较
+		if a < 0 || b < 0 {
+			continue
+		}
+		if a < b {
+			inc += b - a
+		} else {
+			dec += a - b
+		}
+		if 0 < a && 0 < b {
+			smooth -= float64(abs(a - b))
+		}
+	}
+	//单调的行只有一个方向有变化
+	if inc < dec {
+		mono -= float64(inc)
+	} else {
+		mono -= float64(dec)
+	}
+	return mono, smooth
+}
+
+// cornerScore 离某个角落越近的格子权重越大，取最好的角落
+// ranks按行保存每个位置的等级
+func cornerScore(ranks []int, w, h int) float64 {
+	corner := 0.0
+	for _, c := range [][2]int{{0, 0}, {w - 1, 0}, {0, h - 1}, {w - 1, h - 1}} {
+		sum := 0.0
+		for y := 0; y < h; y++ {
+			for x := 0; x < w; x++ {
+				r := ranks[x+y*w]
+				if r <= 0 {
+					continue
+				}
+				d := abs(x-c[0]) + abs(y-c[1])
+				sum += float64(r) / float64(d+1)
+			}
+		}
+		if corner < sum {
+			corner = sum
+		}
+	}
+	return corner
+}
+
+func abs(x int) int {
+	if x < 0 {
+		return -x
+	}
+	return x
+}
@@ -0,0 +1,41 @@
+// server 用HTTP JSON API玩2048 接口见server包的说明
+package main
+
+import (
+	"flag"
+	"gameTest/server"
+	"log"
+	"net/http"
+	"time"
+)
+
+var (
+	addr     = flag.String("addr", "localhost:2048", "监听的地址")
+	maxGames = flag.Int("max-games", 10000, "最多同时保存几局 0为不限制")
+	maxIdle  = flag.Duration("idle", time.Hour, "超过这么久没有移动的局会被删除 0为不删除")
+)
+
+// minExpireInterval 最短多久检查一次不活跃的局
+const minExpireInterval = time.Second
+
+func main() {
+	flag.Parse()
+	s := server.New()
+	s.MaxGames = *maxGames
+	if 0 < *maxIdle {
+		//很短的idle除以10后可能为0，time.Tick会返回nil
+		interval := *maxIdle / 10
+		if interval < minExpireInterval {
+			interval = minExpireInterval
+		}
+		go func() {
+			for range time.Tick(interval) {
+				if n := s.Expire(*maxIdle); 0 < n {
+					log.Printf("删除了%d局不活跃的游戏", n)
+				}
+			}
+		}()
+	}
+	log.Printf("监听 %s", *addr)
+	log.Fatal(http.ListenAndServe(*addr, s))
+}
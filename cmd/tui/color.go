@@ -0,0 +1,136 @@
+package main
+
+import (
+	"fmt"
+	"gameTest/engine"
+	"os"
+	"strings"
+)
+
+// rgb 24位颜色
+type rgb struct {
+	r, g, b uint8
+}
+
+// maxColorRank 有颜色的最大的位置 65536
+const maxColorRank = 16
+
+var (
+	frameColor     = rgb{0xbb, 0xad, 0xa0}
+	wallColor      = rgb{0x5c, 0x53, 0x4b}
+	darkTextColor  = rgb{0x77, 0x6e, 0x65}
+	lightTextColor = rgb{0xf9, 0xf6, 0xf2}
+)
+
+// tileColors 和窗口中gridBackgroundColor一样的格子颜色，半透明的颜色已经和棋盘的底色混合
+// 下标为值在规则数列中的位置 0为空格子
+var tileColors = [maxColorRank + 1]rgb{
+	{0xcd, 0xc0, 0xb4},
+	{0xee, 0xe4, 0xda},
+	{0xed, 0xe0, 0xc8},
+	{0xf2, 0xb1, 0x79},
+	{0xf5, 0x95, 0x63},
+	{0xf6, 0x7c, 0x5f},
+	{0xf6, 0x5e, 0x3b},
+	{0xed, 0xcf, 0x72},
+	{0xed, 0xcc, 0x61},
+	{0xed, 0xc8, 0x50},
+	{0xed, 0xc5, 0x3f},
+	{0xed, 0xc2, 0x2e},
+	{0xaf, 0x7b, 0xa2},
+	{0xaa, 0x67, 0xa3},
+	{0xa8, 0x5d, 0xa3},
+	{0xa5, 0x53, 0xa4},
+	{0xa3, 0x49, 0xa4},
+}
+
+// trueColor 终端是否支持24位颜色 不支持时使用最接近的256色
+var trueColor = func() bool {
+	c := strings.ToLower(os.Getenv("COLORTERM"))
+	return c == "truecolor" || c == "24bit"
+}()
+
+// tileRank 值在规则数列中的位置 限制在颜色表的范围内 空格子为0
+func tileRank(rule engine.MergeRule, value int) int {
+	if value == 0 {
+		return 0
+	}
+	r := rule.Rank(value)
+	if r < 1 {
+		r = 1
+	}
+	if maxColorRank < r {
+		r = maxColorRank
+	}
+	return r
+}
+
+// tileColor 格子的背景色和文字颜色 前两个值用深色的文字
+func tileColor(rule engine.MergeRule, value int) (rgb, rgb) {
+	r := tileRank(rule, value)
+	if r <= 2 {
+		return tileColors[r], darkTextColor
+	}
+	return tileColors[r], lightTextColor
+}
+
+// bg 设置背景色的转义序列
+func bg(c rgb) string {
+	if trueColor {
+		return fmt.Sprintf("\x1b[48;2;%d;%d;%dm", c.r, c.g, c.b)
+	}
+	return fmt.Sprintf("\x1b[48;5;%dm", xterm256(c))
+}
+
+// fg 设置文字颜色的转义序列
+func fg(c rgb) string {
+	if trueColor {
+		return fmt.Sprintf("\x1b[38;2;%d;%d;%dm", c.r, c.g, c.b)
+	}
+	return fmt.Sprintf("\x1b[38;5;%dm", xterm256(c))
+}
+
+// xterm256 256色中最接近的颜色 从6x6x6的颜色立方体和24级灰度中选
+func xterm256(c rgb) int {
+	levels := [6]int{0, 0x5f, 0x87, 0xaf, 0xd7, 0xff}
+	nearest := func(v uint8) int {
+		best := 0
+		for i, l := range levels {
+			if abs(int(v)-l) < abs(int(v)-levels[best]) {
+				best = i
+			}
+		}
+		return best
+	}
+	ri, gi, bi := nearest(c.r), nearest(c.g), nearest(c.b)
+	cube := rgb{uint8(levels[ri]), uint8(levels[gi]), uint8(levels[bi])}
+	//灰度 8, 18, ..., 238
+	avg := (int(c.r) + int(c.g) + int(c.b)) / 3
+	gray := (avg - 8 + 5) / 10
+	if gray < 0 {
+		gray = 0
+	}
+	if 23 < gray {
+		gray = 23
+	}
+	gv := uint8(8 + gray*10)
+	if distance(c, rgb{gv, gv, gv}) < distance(c, cube) {
+		return 232 + gray
+	}
+	return 16 + 36*ri + 6*gi + bi
+}
+
+// distance 两个颜色的距离的平方
+func distance(a, b rgb) int {
+	dr := int(a.r) - int(b.r)
+	dg := int(a.g) - int(b.g)
+	db := int(a.b) - int(b.b)
+	return dr*dr + dg*dg + db*db
+}
+
+func abs(x int) int {
+	if x < 0 {
+		return -x
+	}
+	return x
+}
@@ -0,0 +1,80 @@
+package main
+
+import (
+	"gameTest/engine"
+	"io"
+)
+
+// key 一次按键
+type key int
+
+const (
+	keyNone key = iota
+	keyUp
+	keyRight
+	keyDown
+	keyLeft
+	keyUndo    //u
+	keyNew     //n
+	keyAI      //t 让AI走一步
+	keyQuit    //q Ctrl-C
+	keyUnknown //其他的键
+)
+
+// dir 移动的按键对应的方向
+func (k key) dir() (engine.Dir, bool) {
+	switch k {
+	case keyUp:
+		return engine.DirUp, true
+	case keyRight:
+		return engine.DirRight, true
+	case keyDown:
+		return engine.DirDown, true
+	case keyLeft:
+		return engine.DirLeft, true
+	}
+	return 0, false
+}
+
+// runeKeys 普通字符的按键 WASD和hjkl都可以移动
+var runeKeys = map[byte]key{
+	'w': keyUp, 'a': keyLeft, 's': keyDown, 'd': keyRight,
+	'W': keyUp, 'A': keyLeft, 'S': keyDown, 'D': keyRight,
+	'k': keyUp, 'h': keyLeft, 'j': keyDown, 'l': keyRight,
+	'u': keyUndo, 'U': keyUndo,
+	'n': keyNew, 'N': keyNew,
+	't': keyAI, 'T': keyAI,
+	'q': keyQuit, 'Q': keyQuit,
+	3: keyQuit, //Ctrl-C
+}
+
+// readKey 从原始模式的终端读一次按键
+// 方向键是 ESC [ A 这样的转义序列，一次读出来
+func readKey(r io.Reader) (key, error) {
+	var buf [8]byte
+	n, err := r.Read(buf[:])
+	if err != nil {
+		return keyNone, err
+	}
+	b := buf[:n]
+	if 3 <= len(b) && b[0] == 0x1b && (b[1] == '[' || b[1] == 'O') {
+		switch b[2] {
+		case 'A':
+			return keyUp, nil
+		case 'B':
+			return keyDown, nil
+		case 'C':
+			return keyRight, nil
+		case 'D':
+			return keyLeft, nil
+		}
+		return keyUnknown, nil
+	}
+	if len(b) == 0 {
+		return keyNone, nil
+	}
+	if k, ok := runeKeys[b[0]]; ok {
+		return k, nil
+	}
+	return keyUnknown, nil
+}
@@ -0,0 +1,192 @@
+// tui 在终端中玩2048 和窗口版使用同样的规则
+// 方向键、WASD或者hjkl移动，u撤销，t让AI走一步，n新游戏，q退出
+package main
+
+import (
+	"bufio"
+	"flag"
+	"fmt"
+	"gameTest/ai"
+	"gameTest/engine"
+	"golang.org/x/term"
+	"log"
+	"os"
+	"time"
+)
+
+var (
+	boardSize = flag.Int("size", 4, "正方形棋盘的大小")
+	width     = flag.Int("width", 0, "棋盘的列数 0为和size一样")
+	height    = flag.Int("height", 0, "棋盘的行数 0为和size一样")
+	winTarget = flag.Int("target", 0, "合并出这个值就算赢 0为合并规则的目标")
+	rule      = flag.String("rule", "classic", "合并规则 classic, fibonacci, pow3, threes")
+	seed      = flag.Uint64("seed", 0, "每局的随机种子 0为每局使用新的种子")
+	level     = flag.String("level", "", "棋盘布局文件 '.'为空位置 '#'为墙")
+)
+
+const (
+	minBoardSize = 2 //棋盘最少的行数和列数
+	maxBoardSize = 8 //棋盘最多的行数和列数
+)
+
+func main() {
+	flag.Parse()
+	layout, err := parseLayout()
+	if err != nil {
+		log.Fatal(err)
+	}
+	fd := int(os.Stdin.Fd())
+	if !term.IsTerminal(fd) {
+		log.Fatal("twenty48: stdin is not a terminal")
+	}
+	old, err := term.MakeRaw(fd)
+	if err != nil {
+		log.Fatal(err)
+	}
+	out := bufio.NewWriter(os.Stdout)
+	//使用备用屏幕并隐藏光标，退出时恢复
+	fmt.Fprint(out, "\x1b[?1049h\x1b[?25l")
+	err = run(layout, out)
+	fmt.Fprint(out, "\x1b[0m\x1b[?25h\x1b[?1049l")
+	out.Flush()
+	term.Restore(fd, old)
+	if err != nil {
+		log.Fatal(err)
+	}
+}
+
+// parseLayout 根据命令行参数生成棋盘布局
+func parseLayout() (engine.State, error) {
+	r, err := engine.RuleByName(*rule)
+	if err != nil {
+		return engine.State{}, err
+	}
+	if *level != "" {
+		data, err := os.ReadFile(*level)
+		if err != nil {
+			return engine.State{}, err
+		}
+		layout, err := engine.ParseLayout(string(data))
+		if err != nil {
+			return engine.State{}, err
+		}
+		return layout.WithRule(r), nil
+	}
+	w, h := *boardSize, *boardSize
+	if *width != 0 {
+		w = *width
+	}
+	if *height != 0 {
+		h = *height
+	}
+	if w < minBoardSize || maxBoardSize < w || h < minBoardSize || maxBoardSize < h {
+		return engine.State{}, fmt.Errorf("twenty48: board size %dx%d out of range", w, h)
+	}
+	return engine.New(w, h).WithRule(r), nil
+}
+
+// game 终端中的一局游戏
+type game struct {
+	layout  engine.State
+	state   engine.State
+	rng     *engine.Source
+	undo    []snapshot //撤销的记录
+	won     bool       //是否已经赢过 赢过之后不再提示
+	message string     //显示在棋盘下面的提示
+}
+
+// snapshot 撤销用的记录
+type snapshot struct {
+	state engine.State
+	rng   uint64
+}
+
+// newGame 开始新的一局
+func newGame(layout engine.State) (*game, error) {
+	s := *seed
+	if s == 0 {
+		s = uint64(time.Now().UnixNano())
+	}
+	g := &game{
+		layout: layout,
+		rng:    engine.NewSource(s),
+	}
+	state, err := engine.Start(layout, g.rng)
+	if err != nil {
+		return nil, err
+	}
+	g.state = state
+	return g, nil
+}
+
+// target 胜利的目标
+func (g *game) target() int {
+	if 0 < *winTarget {
+		return *winTarget
+	}
+	return g.state.Rule().Target()
+}
+
+// move 向dir方向移动一步
+func (g *game) move(dir engine.Dir) {
+	prev := snapshot{state: g.state, rng: g.rng.State()}
+	next, _, moved := engine.Step(g.state, dir, g.rng)
+	if !moved {
+		return
+	}
+	g.undo = append(g.undo, prev)
+	g.state = next
+	g.message = ""
+	if !g.won && g.state.Reached(g.target()) {
+		g.won = true
+		g.message = fmt.Sprintf("合并出了%d！ 可以继续玩，n开始新的一局", g.target())
+	}
+}
+
+// undoMove 撤销上一步
+func (g *game) undoMove() {
+	if len(g.undo) == 0 {
+		return
+	}
+	s := g.undo[len(g.undo)-1]
+	g.undo = g.undo[:len(g.undo)-1]
+	g.state = s.state
+	g.rng.SetState(s.rng)
+	g.message = ""
+}
+
+// run 读取按键直到退出
+func run(layout engine.State, out *bufio.Writer) error {
+	g, err := newGame(layout)
+	if err != nil {
+		return err
+	}
+	for {
+		render(out, g)
+		if err := out.Flush(); err != nil {
+			return err
+		}
+		k, err := readKey(os.Stdin)
+		if err != nil {
+			return err
+		}
+		if dir, ok := k.dir(); ok {
+			g.move(dir)
+			continue
+		}
+		switch k {
+		case keyQuit:
+			return nil
+		case keyUndo:
+			g.undoMove()
+		case keyNew:
+			if g, err = newGame(layout); err != nil {
+				return err
+			}
+		case keyAI:
+			if dir, ok := ai.BestMove(g.state, ai.Options{Budget: ai.DefaultBudget}); ok {
+				g.move(dir)
+			}
+		}
+	}
+}
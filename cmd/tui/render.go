@@ -0,0 +1,68 @@
+package main
+
+import (
+	"bufio"
+	"fmt"
+	"gameTest/engine"
+	"strconv"
+	"strings"
+	"unicode/utf8"
+)
+
+const (
+	cellWidth  = 7 //每个格子占几列
+	cellHeight = 3 //每个格子占几行
+	reset      = "\x1b[0m"
+	bold       = "\x1b[1m"
+)
+
+// render 清屏后绘制整个界面 原始模式下换行要用\r\n
+func render(out *bufio.Writer, g *game) {
+	w, h := g.state.Size()
+	rule := g.state.Rule()
+	fmt.Fprint(out, "\x1b[H\x1b[2J")
+	fmt.Fprintf(out, "%s%d%s  分数 %d  种子 %d\r\n\r\n", bold, g.target(), reset, g.state.Score(), g.rng.Seed())
+
+	//格子之间用棋盘的底色隔开
+	gap := bg(frameColor) + " "
+	border := bg(frameColor) + strings.Repeat(" ", w*(cellWidth+1)+1) + reset + "\r\n"
+	out.WriteString(border)
+	for y := 0; y < h; y++ {
+		for line := 0; line < cellHeight; line++ {
+			out.WriteString(gap)
+			for x := 0; x < w; x++ {
+				out.WriteString(cell(rule, g.state.At(x, y), line == cellHeight/2))
+				out.WriteString(gap)
+			}
+			out.WriteString(reset + "\r\n")
+		}
+		out.WriteString(border)
+	}
+	out.WriteString("\r\n")
+
+	switch {
+	case !g.state.CanMove():
+		fmt.Fprintf(out, "%s游戏结束！%s u撤销 n新游戏 q退出\r\n", bold, reset)
+	case g.message != "":
+		out.WriteString(g.message + "\r\n")
+	default:
+		out.WriteString("\r\n")
+	}
+	out.WriteString("方向键/WASD/hjkl移动 u撤销 t让AI走一步 n新游戏 q退出\r\n")
+}
+
+// cell 格子的一行 label为true时在中间写上数字
+func cell(rule engine.MergeRule, v int, label bool) string {
+	if v == engine.Wall {
+		return bg(wallColor) + strings.Repeat(" ", cellWidth)
+	}
+	back, front := tileColor(rule, v)
+	str := ""
+	if label && v != 0 {
+		str = strconv.Itoa(v)
+	}
+	n := utf8.RuneCountInString(str)
+	left := (cellWidth - n) / 2
+	right := cellWidth - n - left
+	return bg(back) + fg(front) + bold + strings.Repeat(" ", left) + str + strings.Repeat(" ", right) + reset
+}
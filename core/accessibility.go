@@ -0,0 +1,221 @@
+package core
+
+import (
+	"github.com/hajimehoshi/ebiten/v2"
+	"image/color"
+	"math"
+)
+
+const (
+	patternKinds = 8    //图案的种类 等级超过种类数时在左上角加圆点
+	patternAlpha = 0x60 //图案的透明度
+)
+
+// palette 替换主题中格子颜色的配色 色弱的玩家也能区分相邻的等级
+type palette struct {
+	name  string
+	label string     //设置中显示的名字
+	tiles []hexColor //每个等级的格子颜色 第一个为等级1
+}
+
+// palettes 可以选择的配色
+// 相邻的等级亮度差别大，每组内只用对应色弱的玩家能区分的色相
+var palettes = []*palette{
+	{
+		//绿色弱 蓝色和橙色
+		name:  "deuteranopia",
+		label: "绿色弱",
+		tiles: hexColors(
+			"#dbe9f6", "#bad6eb", "#89bedc", "#539ecd", "#2b7bba", "#0b559f", "#08306b",
+			"#fee391", "#fec44f", "#fe9929", "#ec7014", "#cc4c02", "#993404", "#662506",
+			"#3f007d", "#1b0a3a",
+		),
+	},
+	{
+		//红色弱 红色看起来很暗，用蓝色和黄色
+		name:  "protanopia",
+		label: "红色弱",
+		tiles: hexColors(
+			"#e0ecf4", "#bfd3e6", "#9ebcda", "#8c96c6", "#6a7fb8", "#3f5aa8", "#1f3a8a",
+			"#fff7bc", "#fee391", "#fec44f", "#f0b400", "#c89600", "#8c6d00", "#5c4a00",
+			"#2d2d2d", "#000000",
+		),
+	},
+	{
+		//蓝黄色弱 红色和青色
+		name:  "tritanopia",
+		label: "蓝黄色弱",
+		tiles: hexColors(
+			"#fde0dd", "#fcc5c0", "#fa9fb5", "#f768a1", "#dd3497", "#ae017e", "#7a0177",
+			"#e0f3f3", "#a8dede", "#6cc4c4", "#35a3a3", "#1a8080", "#0b5e5e", "#063f3f",
+			"#2d2d2d", "#000000",
+		),
+	},
+}
+
+// hexColors 解析内置的颜色 写错时panic
+func hexColors(strs ...string) []hexColor {
+	colors := make([]hexColor, len(strs))
+	for i, s := range strs {
+		if err := colors[i].UnmarshalText([]byte(s)); err != nil {
+			panic(err)
+		}
+	}
+	return colors
+}
+
+// paletteByName 根据名字找到配色 空为主题自己的颜色
+func paletteByName(name string) *palette {
+	for _, p := range palettes {
+		if p.name == name {
+			return p
+		}
+	}
+	return nil
+}
+
+// tileColor 等级为rank的格子的颜色
+func (p *palette) tileColor(rank int) color.Color {
+	if rank <= len(p.tiles) {
+		return p.tiles[rank-1]
+	}
+	return generatedColor(p.tiles, rank)
+}
+
+// accessibility 辅助显示的设置
+type accessibility struct {
+	palette      *palette //nil为主题的颜色
+	highContrast bool     //格子加粗描边，数字加粗并且只用黑白两色
+	patterns     bool     //格子上画每个等级不同的图案，不看颜色也能区分
+}
+
+// access 当前的辅助显示设置
+var access accessibility
+
+// tileColors 等级为rank的格子的颜色和文字颜色
+func tileColors(rank int) (color.Color, color.Color) {
+	bg, fg := theme.tileColor(rank), theme.textColor(rank)
+	if access.palette != nil {
+		bg = access.palette.tileColor(rank)
+		fg = theme.contrastText(bg)
+	}
+	if access.highContrast {
+		fg = contrastColor(bg)
+	}
+	return bg, fg
+}
+
+// contrastColor 在c上对比度最高的颜色 黑色或者白色
+func contrastColor(c color.Color) color.Color {
+	if 0.5 < luminance(color.NRGBAModel.Convert(c).(color.NRGBA)) {
+		return color.Black
+	}
+	return color.White
+}
+
+// outlineWidth 高对比度时格子描边的宽度
+func outlineWidth(tileSize int) int {
+	w := tileSize / 20
+	if w < 2 {
+		w = 2
+	}
+	return w
+}
+
+// boldOffset 加粗数字时文字错开绘制的像素
+func boldOffset(tileSize int) int {
+	w := tileSize / 40
+	if w < 1 {
+		w = 1
+	}
+	return w
+}
+
+// patternImageKey 图案图片的大小、圆角半径和等级
+type patternImageKey struct {
+	size   int
+	radius int
+	rank   int
+}
+
+// patternImageCache 不同大小和等级的图案
+var patternImageCache = map[patternImageKey]*ebiten.Image{}
+
+// patternImage 等级为rank的格子上的白色图案 和tileImage一样大小和圆角
+// 前patternKinds个等级各用一种图案，之后图案循环，左上角的圆点数表示第几轮
+func patternImage(size, rank int) *ebiten.Image {
+	if size < 1 {
+		size = 1
+	}
+	key := patternImageKey{size: size, radius: int(theme.CornerRadius * float64(size)), rank: rank}
+	if img, ok := patternImageCache[key]; ok {
+		return img
+	}
+	kind := (rank - 1) % patternKinds
+	dots := (rank - 1) / patternKinds
+	//图案的间距 每个格子大约六条线
+	s := float64(size) / 6
+	if s < 4 {
+		s = 4
+	}
+	dotR := s * 0.35
+	pix := make([]byte, 4*size*size)
+	for y := 0; y < size; y++ {
+		for x := 0; x < size; x++ {
+			px, py := float64(x)+0.5, float64(y)+0.5
+			a := 0.0
+			if patternAt(kind, px, py, s) {
+				a = 0.5
+			}
+			//圆点周围留空，和图案分开
+			for i := 0; i < dots; i++ {
+				cx, cy := s*0.8+float64(i)*dotR*3, s*0.8
+				if float64(size) < cx+dotR {
+					break
+				}
+				d := math.Hypot(px-cx, py-cy)
+				switch {
+				case d < dotR:
+					a = 1
+				case d < dotR*1.6:
+					a = 0
+				}
+			}
+			v := byte(math.Round(a * tileCoverage(x, y, size, float64(key.radius)) * 0xff))
+			i := 4 * (x + y*size)
+			pix[i], pix[i+1], pix[i+2], pix[i+3] = v, v, v, v
+		}
+	}
+	img := ebiten.NewImage(size, size)
+	img.WritePixels(pix)
+	patternImageCache[key] = img
+	return img
+}
+
+// patternAt 点(px,py)是否在第kind种图案上 s为图案的间距
+func patternAt(kind int, px, py, s float64) bool {
+	line := func(v float64) bool {
+		return math.Mod(v+8*s, s) < s*0.3
+	}
+	switch kind {
+	case 0: //横线
+		return line(py)
+	case 1: //竖线
+		return line(px)
+	case 2: //斜线
+		return line((px + py) / math.Sqrt2)
+	case 3: //反斜线
+		return line((px - py) / math.Sqrt2)
+	case 4: //方格
+		return line(px) || line(py)
+	case 5: //交叉斜线
+		return line((px+py)/math.Sqrt2) || line((px-py)/math.Sqrt2)
+	case 6: //圆点
+		cx := math.Floor(px/s)*s + s/2
+		cy := math.Floor(py/s)*s + s/2
+		return math.Hypot(px-cx, py-cy) < s*0.25
+	case 7: //棋盘格
+		return int(math.Floor(px/s)+math.Floor(py/s))%2 == 0
+	}
+	panic("not reach")
+}
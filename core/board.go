@@ -2,9 +2,9 @@ package core
 
 import (
 	"errors"
+	"gameTest/engine"
 	"github.com/hajimehoshi/ebiten/v2"
-	"math/rand"
-	"sort"
+	"time"
 )
 
 var taskTerminated = errors.New("twenty48: task terminated")
@@ -13,13 +13,25 @@ type task func() error
 
 // Board 游戏棋盘
 type Board struct {
-	x          int //棋盘位置
-	y          int //棋盘位置
-	w          int //棋盘宽高
-	h          int //棋盘宽高
-	tileSize   int //格子的大小
-	tileMargin int //格子中间的距离
-	size       int //棋盘大小
+	x          int               //棋盘位置
+	y          int               //棋盘位置
+	w          int               //棋盘宽高
+	h          int               //棋盘宽高
+	tileSize   int               //格子的大小
+	tileMargin int               //格子中间的距离
+	cols       int               //棋盘的列数
+	rows       int               //棋盘的行数
+	state      engine.State      //棋盘的规则状态
+	last       engine.MoveResult //最后一次移动的结果
+	history    history           //撤销和重做的记录
+	rng        *engine.Source    //生成格子的随机数
+	moves      int               //已经走了几步
+	changed    bool              //上次保存后棋盘是否有变化
+	record     *engine.Replay    //这局的录像 没有录像时为nil
+	started    time.Time         //开局的时间 读档时按录像的用时调整
+	begun      time.Time         //开局的真实时间 读档后不变，排行榜用来区分不同的局
+	hint       *hint             //显示的提示 没有提示时为nil
+	queue      moveQueue         //动画时收到的移动
 	grids      map[*Grid]struct{}
 	tasks      []task
 	image      *ebiten.Image
@@ -31,31 +43,76 @@ type Board struct {
 // 12 13 14 15
 
 // NewBoard 初始化棋盘
-func NewBoard(screenWidth, screenHeight, size, tileSize, tileMargin int) (*Board, error) {
+// layout 棋盘的大小和墙的位置，格子的大小根据画布的大小计算
+// rng 生成格子的随机数，同样的布局、同样种子的随机数和同样的移动总是得到同样的游戏
+func NewBoard(screenWidth, screenHeight int, layout engine.State, rng *engine.Source) (*Board, error) {
+	b := newBoard(screenWidth, screenHeight, layout, rng)
+	//第一次增加两个格子
+	state, err := engine.Start(layout, rng)
+	if err != nil {
+		return nil, err
+	}
+	b.state = state
+	b.grids = newGrids(state.Tiles(), true)
+	return b, nil
+}
+
+// newGrids 把格子的列表转换为绘制用的格子集合
+// pop为false时格子直接显示，没有弹出的动画
+func newGrids(tiles []engine.Tile, pop bool) map[*Grid]struct{} {
+	grids := make(map[*Grid]struct{}, len(tiles))
+	for _, t := range tiles {
+		g := NewGrid(t.Value, t.X, t.Y)
+		if !pop {
+			g.startPoppingCount = 0
+		}
+		grids[g] = struct{}{}
+	}
+	return grids
+}
+
+// newBoard 初始化没有格子的棋盘
+func newBoard(screenWidth, screenHeight int, layout engine.State, rng *engine.Source) *Board {
+	cols, rows := layout.Size()
 	b := &Board{
-		size:       size,
-		tileSize:   tileSize,
+		cols:       cols,
+		rows:       rows,
+		tileSize:   fitTileSize(screenWidth, screenHeight, cols, rows),
 		tileMargin: tileMargin,
+		state:      layout.Layout(),
 		grids:      map[*Grid]struct{}{},
+		rng:        rng,
+		changed:    true,
+		record:     engine.NewReplay(layout, rng.Seed()),
+		started:    time.Now(),
+		begun:      time.Now(),
 	}
 	//设置棋盘位置
 	b.setXY(screenWidth, screenHeight)
-	//第一次增加两个格子
-	for i := 0; i < 2; i++ {
-		//给棋盘增加格子
-		if err := b.addRandomGrid(); err != nil {
-			return nil, err
-		}
-	}
 	b.image = ebiten.NewImage(b.Size())
-	return b, nil
+	return b
+}
+
+// fitTileSize 计算让cols列rows行的棋盘放进画布的格子大小
+// 棋盘上方留出hudHeight的空白，格子最大为maxTileSize
+func fitTileSize(screenWidth, screenHeight, cols, rows int) int {
+	w := screenWidth - 2*floorBoard
+	h := screenHeight - hudHeight - floorBoard
+	ts := (w - (cols+1)*tileMargin) / cols
+	if th := (h - (rows+1)*tileMargin) / rows; th < ts {
+		ts = th
+	}
+	if maxTileSize < ts {
+		ts = maxTileSize
+	}
+	return ts
 }
 
 func (b *Board) setXY(ScreenWidth, ScreenHeight int) {
 	//棋盘的大小
 	//4*80+(4+1)*4 格子大小和边框大小
-	b.w = b.size*b.tileSize + (b.size+1)*b.tileMargin
-	b.h = b.w
+	b.w = b.cols*b.tileSize + (b.cols+1)*b.tileMargin
+	b.h = b.rows*b.tileSize + (b.rows+1)*b.tileMargin
 
 	//棋盘靠下 左右居中
 	b.x = (ScreenWidth - b.w) / 2
@@ -64,45 +121,20 @@ func (b *Board) setXY(ScreenWidth, ScreenHeight int) {
 
 // addRandomGrid 增加随机的格子
 func (b *Board) addRandomGrid() error {
-	//初始化一个棋盘的位置
-	cells := make([]bool, b.size*b.size)
 	for grid := range b.grids {
 		//判断已有的格子中是否存在有步数的格子
 		if grid.IsMoving() {
 			panic("not reach")
 		}
-		//计算格子的位置
-		i := grid.current.x + grid.current.y*b.size
-		//棋盘中该位置有值
-		cells[i] = true
-	}
-	//初始化一个没有棋子的空格子集
-	var availableCells []int
-	for i, b := range cells {
-		//格子有值就跳过
-		if b {
-			continue
-		}
-		//空值的写入集合
-		availableCells = append(availableCells, i)
 	}
-	//判断是否还有空格子
-	if len(availableCells) == 0 {
-		return errors.New("twenty48: there is no space to add a new tile")
+	//在棋盘的空位置随机增加一个格子
+	state, tile, err := b.state.Spawn(b.rng)
+	if err != nil {
+		return err
 	}
-	//随机取出一个位置
-	c := availableCells[rand.Intn(len(availableCells))]
-	//格子的值为2
-	v := 2
-	// 1/10 的概率为4
-	if rand.Intn(10) == 0 {
-		v = 4
-	}
-	// 计算格子在棋盘中的x,y轴
-	x := c % b.size
-	y := c / b.size
+	b.state = state
 	// 初始化格子
-	t := NewGrid(v, x, y)
+	t := NewGrid(tile.Value, tile.X, tile.Y)
 	// 写入棋盘
 	b.grids[t] = struct{}{}
 	return nil
@@ -116,8 +148,17 @@ func (b *Board) Update(input *Input) error {
 			return err
 		}
 	}
+	//动画时收到的移动先缓存，快进时马上走完动画执行
+	dir, ok := Dir(0), false
+	if input != nil {
+		dir, ok = input.Dir()
+	}
+	if ok && 0 < len(b.tasks) && !b.queue.fastForward {
+		b.queue.push(dir)
+		ok = false
+	}
 	//判断是否有任务
-	if 0 < len(b.tasks) {
+	if 0 < len(b.tasks) && !ok {
 		//取出第一个任务并执行
 		t := b.tasks[0]
 		if err := t(); err == taskTerminated {
@@ -129,17 +170,18 @@ func (b *Board) Update(input *Input) error {
 		return nil
 	}
 
-	//是否在棋盘上移动
-	var x, y, width, height int
-	width, height = b.Size()
-	x, y = b.XY()
-	if input.InTheArea(x, y, width, height) {
-		//计算输入的移动
-		if dir, ok := input.Dir(); ok {
-			//棋盘开始移动
-			if err := b.Move(dir); err != nil {
-				return err
-			}
+	//停下来后先执行缓存的移动 这一帧的输入排在后面
+	if queued, has := b.queue.pop(); has {
+		if ok {
+			b.queue.push(dir)
+		}
+		dir, ok = queued, true
+	}
+	//按键和手柄在哪里都可以移动，滑动要从棋盘上开始
+	if ok {
+		//棋盘开始移动
+		if err := b.Move(dir); err != nil {
+			return err
 		}
 	}
 
@@ -148,13 +190,24 @@ func (b *Board) Update(input *Input) error {
 
 // Move 将棋盘的移动入队
 func (b *Board) Move(dir Dir) error {
+	//上一步还没走完的直接走完，保证撤销记录是完整的
+	b.finishTasks()
 	for t := range b.grids {
 		t.stopAnimation()
 	}
+	prev := b.snapshot()
 	//移动格子
 	if !b.MoveGrids(dir) {
 		return nil
 	}
+	//记录移动前的状态
+	b.history.push(prev)
+	b.moves++
+	//走了一步之后提示就过时了
+	b.hint = nil
+	if b.record != nil {
+		b.record.Record(dir, time.Since(b.started))
+	}
 	//移动成功
 	b.tasks = append(b.tasks, func() error {
 		//将每个格子判断是否需要移动的写入任务
@@ -186,161 +239,121 @@ func (b *Board) Move(dir Dir) error {
 		//更新格子
 		b.grids = nextTiles
 		//增加随机的格子
-		if err := b.addRandomGrid(); err != nil {
+		//没有空位时由游戏判断是否结束，这里不返回错误
+		if err := b.addRandomGrid(); err != nil && !errors.Is(err, engine.ErrNoSpace) {
 			return err
 		}
+		//这一步走完了
+		b.changed = true
 		return taskTerminated
 	})
 	return nil
 }
 
-// gridAt 找到该位置的格子
-func (b *Board) gridAt(x, y int) *Grid {
-	var result *Grid
+// gridIndex 按位置索引所有的格子 下标为x+y*cols
+// 移动时只遍历一次格子集合，不用每个位置都查找一遍
+func (b *Board) gridIndex() []*Grid {
+	index := make([]*Grid, b.cols*b.rows)
 	for t := range b.grids {
-		//如果当前格子的x轴不等于x，或者y轴不等于y，跳过
-		if t.current.x != x || t.current.y != y {
-			continue
-		}
-		//格子有值，报错
-		if result != nil {
+		i := t.current.x + t.current.y*b.cols
+		//同一个位置有两个格子，报错
+		if index[i] != nil {
 			panic("not reach")
 		}
-		result = t
+		index[i] = t
 	}
-	return result
+	return index
 }
 
-// currentOrNextGridAt 找到移动后的格子的位置
-func (b *Board) currentOrNextGridAt(x, y int) *Grid {
-	var result *Grid
-	for t := range b.grids {
-		//移动的步数是否大于0
-		if t.IsMoving() {
-			//判断移动后位置的 x轴不等于x的，y轴不等于y的，值等于0的跳过
-			if t.next.x != x || t.next.y != y || t.next.value == 0 {
-				continue
-			}
-		} else {
-			//判断当前位置的 x轴不等于x的，y轴不等于y的跳过
-			if t.current.x != x || t.current.y != y {
-				continue
-			}
+// MoveGrids 移动格子集合 返回移动是否成功
+// 移动的规则由engine计算，这里只根据结果设置每个格子的动画
+func (b *Board) MoveGrids(dir Dir) bool {
+	state, result := b.state.Move(dir)
+	if !result.Moved {
+		return false
+	}
+	index := b.gridIndex()
+	for _, m := range result.Moves {
+		//找到移动前位置的格子
+		t := index[m.From.X+m.From.Y*b.cols]
+		if t == nil {
+			panic("not reach")
 		}
-		//格子有值的报错
-		if result != nil {
+		//如果移动后的格子不为空 报错
+		if t.next != (GridData{}) {
 			panic("not reach")
 		}
-		result = t
+		//被合并的格子下一步的值为0
+		t.next = GridData{
+			value: m.Next,
+			x:     m.To.X,
+			y:     m.To.Y,
+		}
+		t.movingCount = maxMovingCount
 	}
-	return result
+	b.state = state
+	b.last = result
+	return true
 }
 
-// MoveGrids 移动格子集合 返回移动是否成功
-func (b *Board) MoveGrids(dir Dir) bool {
-	tiles := b.grids
-	size := b.size
-	//移动格子的矢量坐标
-	vx, vy := dir.Vector()
-	var tx []int
-	var ty []int
-	//计算棋盘每个格子的坐标
-	for i := 0; i < size; i++ {
-		tx = append(tx, i)
-		ty = append(ty, i)
-	}
-	//x轴是否需要移动
-	if vx > 0 {
-		//对x轴逆序排序
-		sort.Sort(sort.Reverse(sort.IntSlice(tx)))
-	}
-	//y轴是否需要移动
-	if vy > 0 {
-		//对y轴逆序排序
-		sort.Sort(sort.Reverse(sort.IntSlice(ty)))
-	}
-	//定义一个标志位：是否需要移动
-	moved := false
-	//对y轴进行循环
-	for _, j := range ty {
-		//对x轴进行循环
-		for _, i := range tx {
-			//找到该位置的格子
-			t := b.gridAt(i, j)
-			if t == nil { //格子为空跳出循环
-				continue
-			}
-			//如果移动后的格子不为空 报错
-			if t.next != (GridData{}) {
-				panic("not reach")
-			}
-			//如果移动步数不为0 报错
-			if t.IsMoving() {
-				panic("not reach")
-			}
-			// (ii, jj) 是格子t的下一个位置。
-			// (ii, jj) 被更新，直到找到可合并切片或格子t不能再移动了。
-			ii := i
-			jj := j
-			for {
-				//计算移动后的位置
-				ni := ii + vx
-				nj := jj + vy
-				//移动后的位置不能超过框
-				if ni < 0 || ni >= size || nj < 0 || nj >= size {
-					break
-				}
-				//找到移动后的格子的位置
-				tt := b.currentOrNextGridAt(ni, nj)
-				if tt == nil { //格子等于空的
-					//格子移动后的位置
-					ii = ni
-					jj = nj
-					//标志，需要位移
-					moved = true
-					continue
-				}
-				//当前格子的值不等于移动后的位置的值，跳过
-				if t.current.value != tt.current.value {
-					break
-				}
-				//移动后位置的移动值大于0的，并且当前值不等于移动后值的跳过
-				if 0 < tt.movingCount && tt.current.value != tt.next.value {
-					// tt is already being merged with another tile.
-					// Break here without updating (ii, jj).
-					break
-				}
-				ii = ni
-				jj = nj
-				moved = true
-				break
-			}
-			// 下一步是格子t的下一状态。
-			next := GridData{}
-			next.value = t.current.value
-			// 如果下一个位置(II，JJ)有格子，则应为可合并。让我们合并吧。
-			if tt := b.currentOrNextGridAt(ii, jj); tt != t && tt != nil {
-				next.value = t.current.value + tt.current.value
-				tt.next.value = 0
-				tt.next.x = ii
-				tt.next.y = jj
-				tt.movingCount = maxMovingCount
-			}
-			next.x = ii
-			next.y = jj
-			if t.current != next {
-				t.next = next
-				t.movingCount = maxMovingCount
-			}
-		}
-	}
-	if !moved {
-		for t := range tiles {
-			t.next = GridData{}
-			t.movingCount = 0
-		}
+// IsSettled 棋盘是否已经停下来 没有进行中的动画、任务和缓存的移动
+func (b *Board) IsSettled() bool {
+	return len(b.tasks) == 0 && len(b.queue.dirs) == 0
+}
+
+// Seed 这局的随机种子
+func (b *Board) Seed() uint64 {
+	return b.rng.Seed()
+}
+
+// Replay 这局的录像 没有录像时为nil
+func (b *Board) Replay() *engine.Replay {
+	return b.record
+}
+
+// Moves 已经走了几步
+func (b *Board) Moves() int {
+	return b.moves
+}
+
+// Duration 这局用了多久 到最后一步为止
+func (b *Board) Duration() time.Duration {
+	if b.record != nil && 0 < len(b.record.Moves) {
+		return time.Duration(b.record.Moves[len(b.record.Moves)-1].Time) * time.Millisecond
 	}
-	return moved
+	return time.Since(b.started)
+}
+
+// takeChanged 上次调用后棋盘是否有变化 用来判断是否需要保存
+func (b *Board) takeChanged() bool {
+	changed := b.changed
+	b.changed = false
+	return changed
+}
+
+// Score 当前的分数
+func (b *Board) Score() int {
+	return b.state.Score()
+}
+
+// LastMove 最后一次移动的结果，包括得分和合并的列表
+func (b *Board) LastMove() engine.MoveResult {
+	return b.last
+}
+
+// State 棋盘当前的规则状态
+func (b *Board) State() engine.State {
+	return b.state
+}
+
+// Bitboard 棋盘当前状态的Bitboard 不是4x4的经典棋盘时返回false
+func (b *Board) Bitboard() (engine.Bitboard, bool) {
+	return engine.NewBitboard(b.state)
+}
+
+// Dims 棋盘的列数和行数
+func (b *Board) Dims() (int, int) {
+	return b.cols, b.rows
 }
 
 // Size 棋盘的大小
@@ -355,18 +368,22 @@ func (b *Board) XY() (int, int) {
 
 func (b *Board) Draw() {
 	//设置棋盘颜色
-	b.image.Fill(frameColor)
-	for j := 0; j < b.size; j++ {
-		for i := 0; i < b.size; i++ {
-			v := 0
+	b.image.Fill(theme.Board)
+	for j := 0; j < b.rows; j++ {
+		for i := 0; i < b.cols; i++ {
 			op := &ebiten.DrawImageOptions{}
 			//计算每个格子的左边坐标，上坐标
 			x := i*b.tileSize + (i+1)*b.tileMargin
 			y := j*b.tileSize + (j+1)*b.tileMargin
 			op.GeoM.Translate(float64(x), float64(y))
-			op.ColorScale.ScaleWithColor(gridBackgroundColor(v))
+			//墙
+			if b.state.IsWall(i, j) {
+				b.drawWall(x, y)
+				continue
+			}
+			op.ColorScale.ScaleWithColor(theme.Empty)
 			//每个空白格子
-			b.image.DrawImage(gridImage, op)
+			b.image.DrawImage(tileImage(b.tileSize), op)
 		}
 	}
 	animatingTiles := map[*Grid]struct{}{}
@@ -380,10 +397,28 @@ func (b *Board) Draw() {
 	}
 	//对没有操作的格子渲染
 	for t := range nonAnimatingTiles {
-		t.Draw(b.image)
+		t.Draw(b.image, b.tileSize, b.tileMargin, b.state.Rule())
 	}
 	//对有操作的格子渲染
 	for t := range animatingTiles {
-		t.Draw(b.image)
+		t.Draw(b.image, b.tileSize, b.tileMargin, b.state.Rule())
+	}
+	//提示画在格子上面
+	if b.hint != nil {
+		b.drawHint()
 	}
 }
+
+// drawWall 在(x,y)绘制墙 深色的格子中间再画一个框
+func (b *Board) drawWall(x, y int) {
+	op := &ebiten.DrawImageOptions{}
+	op.GeoM.Translate(float64(x), float64(y))
+	op.ColorScale.ScaleWithColor(theme.Wall)
+	b.image.DrawImage(tileImage(b.tileSize), op)
+
+	inset := b.tileSize / 5
+	op = &ebiten.DrawImageOptions{}
+	op.GeoM.Translate(float64(x+inset), float64(y+inset))
+	op.ColorScale.ScaleWithColor(theme.WallInner)
+	b.image.DrawImage(tileImage(b.tileSize-2*inset), op)
+}
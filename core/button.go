@@ -0,0 +1,113 @@
+package core
+
+import (
+	"github.com/hajimehoshi/ebiten/v2"
+	"github.com/hajimehoshi/ebiten/v2/inpututil"
+	"image/color"
+)
+
+const (
+	buttonWidth  = 140 //按钮的宽
+	buttonHeight = 44  //按钮的高
+)
+
+var (
+	buttonImage = ebiten.NewImage(1, 1)
+)
+
+// 初始化按钮
+func init() {
+	buttonImage.Fill(color.White)
+}
+
+// button 可以点击的按钮
+type button struct {
+	x        int        //按钮位置
+	y        int        //按钮位置
+	w        int        //按钮宽高
+	h        int        //按钮宽高
+	label    string     //按钮上的文字
+	key      ebiten.Key //快捷键 -1为没有 绑定给了其他操作时不响应
+	fixed    bool       //快捷键总是响应 用于输入文字和等待新按键的画面，这时不使用按键绑定
+	disabled bool       //不可用的按钮不响应点击
+	bound    bool       //是否响应binding绑定的按键
+	binding  Action     //按下这个操作绑定的按键时也执行动作
+	action   func() error
+}
+
+// newButton 初始化按钮
+func newButton(label string, key ebiten.Key, action func() error) *button {
+	return &button{
+		w:      buttonWidth,
+		h:      buttonHeight,
+		label:  label,
+		key:    key,
+		action: action,
+	}
+}
+
+// newActionButton 初始化按钮 按下a绑定的按键时执行动作
+func newActionButton(label string, a Action, action func() error) *button {
+	b := newButton(label, -1, action)
+	b.bound = true
+	b.binding = a
+	return b
+}
+
+// setXY 设置按钮位置
+func (b *button) setXY(x, y int) {
+	b.x = x
+	b.y = y
+}
+
+// Update 点击按钮或按下快捷键时执行按钮的动作
+func (b *button) Update(input *Input) error {
+	if b.disabled {
+		return nil
+	}
+	pressed := input.TappedIn(b.x, b.y, b.w, b.h)
+	if b.keyEnabled(input.Keymap()) && inpututil.IsKeyJustPressed(b.key) {
+		pressed = true
+	}
+	if b.bound && input.Pressed(b.binding) {
+		pressed = true
+	}
+	if !pressed {
+		return nil
+	}
+	return b.action()
+}
+
+// keyEnabled 快捷键是否响应
+// 快捷键绑定给了其他操作时让给那个操作，例如WASD方案中S是向下，不会打开统计
+func (b *button) keyEnabled(m Keymap) bool {
+	if b.key < 0 {
+		return false
+	}
+	if b.fixed {
+		return true
+	}
+	a, ok := m.action(b.key)
+	return !ok || b.bound && a == b.binding
+}
+
+// newBackButton 返回上一个画面的按钮 Esc和暂停绑定的按键都可以返回
+func newBackButton(label string, action func() error) *button {
+	b := newActionButton(label, ActionPause, action)
+	b.key = ebiten.KeyEscape
+	return b
+}
+
+// Draw 绘制按钮
+func (b *button) Draw(dst *ebiten.Image) {
+	op := &ebiten.DrawImageOptions{}
+	op.GeoM.Scale(float64(b.w), float64(b.h))
+	op.GeoM.Translate(float64(b.x), float64(b.y))
+	op.ColorScale.ScaleWithColor(theme.Button)
+	//不可用的按钮变淡
+	if b.disabled {
+		op.ColorScale.ScaleAlpha(0.4)
+	}
+	dst.DrawImage(buttonImage, op)
+	drawTextCenter(dst, b.label, mplusSmallFont, b.x, b.y, b.w, b.h, theme.ButtonText)
+}
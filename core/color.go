@@ -1,60 +1,63 @@
 package core
 
-import "image/color"
-
-var (
-	backgroundColor = color.RGBA{0xfa, 0xf8, 0xef, 0xff}
-	frameColor      = color.RGBA{0xbb, 0xad, 0xa0, 0xff}
+import (
+	"gameTest/engine"
+	"github.com/hajimehoshi/ebiten/v2"
+	"math"
 )
 
-// gridColor
-func gridColor(value int) color.Color {
-	switch value {
-	case 2, 4:
-		return color.RGBA{0x77, 0x6e, 0x65, 0xff}
-	case 8, 16, 32, 64, 128, 256, 512, 1024, 2048, 4096, 8192, 16384, 32768, 65536:
-		return color.RGBA{0xf9, 0xf6, 0xf2, 0xff}
+// tileRank 值在规则数列中的位置 用来取主题中的颜色 没有上限
+func tileRank(rule engine.MergeRule, value int) int {
+	r := rule.Rank(value)
+	if r < 1 {
+		r = 1
+	}
+	return r
+}
+
+// tileImageKey 圆角格子图片的大小和圆角半径
+type tileImageKey struct {
+	size   int
+	radius int
+}
+
+// tileImageCache 不同大小的圆角格子
+var tileImageCache = map[tileImageKey]*ebiten.Image{}
+
+// tileImage 大小为size的白色格子 圆角半径按当前主题的比例
+// 绘制时用ColorScale上色
+func tileImage(size int) *ebiten.Image {
+	if size < 1 {
+		size = 1
+	}
+	key := tileImageKey{size: size, radius: int(theme.CornerRadius * float64(size))}
+	if img, ok := tileImageCache[key]; ok {
+		return img
+	}
+	r := float64(key.radius)
+	pix := make([]byte, 4*size*size)
+	for y := 0; y < size; y++ {
+		for x := 0; x < size; x++ {
+			//白色 预乘透明度
+			v := byte(math.Round(tileCoverage(x, y, size, r) * 0xff))
+			i := 4 * (x + y*size)
+			pix[i], pix[i+1], pix[i+2], pix[i+3] = v, v, v, v
+		}
 	}
-	panic("not reach")
+	img := ebiten.NewImage(size, size)
+	img.WritePixels(pix)
+	tileImageCache[key] = img
+	return img
 }
 
-// gridBackgroundColor 不同数字的格子对应的颜色
-func gridBackgroundColor(value int) color.Color {
-	switch value {
-	case 0:
-		return color.NRGBA{0xee, 0xe4, 0xda, 0x59}
-	case 2:
-		return color.RGBA{0xee, 0xe4, 0xda, 0xff}
-	case 4:
-		return color.RGBA{0xed, 0xe0, 0xc8, 0xff}
-	case 8:
-		return color.RGBA{0xf2, 0xb1, 0x79, 0xff}
-	case 16:
-		return color.RGBA{0xf5, 0x95, 0x63, 0xff}
-	case 32:
-		return color.RGBA{0xf6, 0x7c, 0x5f, 0xff}
-	case 64:
-		return color.RGBA{0xf6, 0x5e, 0x3b, 0xff}
-	case 128:
-		return color.RGBA{0xed, 0xcf, 0x72, 0xff}
-	case 256:
-		return color.RGBA{0xed, 0xcc, 0x61, 0xff}
-	case 512:
-		return color.RGBA{0xed, 0xc8, 0x50, 0xff}
-	case 1024:
-		return color.RGBA{0xed, 0xc5, 0x3f, 0xff}
-	case 2048:
-		return color.RGBA{0xed, 0xc2, 0x2e, 0xff}
-	case 4096:
-		return color.NRGBA{0xa3, 0x49, 0xa4, 0x7f}
-	case 8192:
-		return color.NRGBA{0xa3, 0x49, 0xa4, 0xb2}
-	case 16384:
-		return color.NRGBA{0xa3, 0x49, 0xa4, 0xcc}
-	case 32768:
-		return color.NRGBA{0xa3, 0x49, 0xa4, 0xe5}
-	case 65536:
-		return color.NRGBA{0xa3, 0x49, 0xa4, 0xff}
+// tileCoverage 像素(x,y)被大小为size、圆角半径为r的格子覆盖的比例
+// 用像素中心到最近的圆角圆心的距离计算，边缘抗锯齿
+func tileCoverage(x, y, size int, r float64) float64 {
+	if r <= 0 {
+		return 1
 	}
-	panic("not reach")
+	px, py := float64(x)+0.5, float64(y)+0.5
+	cx := math.Max(r, math.Min(float64(size)-r, px))
+	cy := math.Max(r, math.Min(float64(size)-r, py))
+	return math.Max(0, math.Min(1, r-math.Hypot(px-cx, py-cy)+0.5))
 }
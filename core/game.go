@@ -1,36 +1,255 @@
 package core
 
 import (
+	"gameTest/ai"
 	"github.com/hajimehoshi/ebiten/v2"
+	"log"
+	"strconv"
 )
 
 const (
-	floorBoard = 20
-	boardSize  = 4
+	floorBoard       = 20
+	DefaultBoardSize = 4 //默认的棋盘大小
+	MinBoardSize     = 2 //棋盘最少的行数和列数
+	MaxBoardSize     = 8 //棋盘最多的行数和列数
 )
 
 type Game struct {
 	ScreenWidth  int
 	ScreenHeight int
+	options      Options
 	input        *Input
 	board        *Board
+	best         int       //最高分
+	gain         int       //最近一次移动得到的分数
+	gainCount    int       //得分提示还要显示几帧
+	keepPlaying  bool      //赢了之后是否继续游戏
+	undoButton   *button   //撤销按钮
+	redoButton   *button   //重做按钮
+	menuButton   *button   //菜单按钮
+	playback     *playback //回放录像 不在回放时为nil
+	hintButton   *button   //提示按钮
+	hintWanted   bool      //棋盘停下来后显示提示
+	hintSearch   *search   //正在计算的提示 没有时为nil
+	autoSearch   *search   //自动玩正在计算的一步 没有时为nil
+	autoplay     bool      //是否由AI自动玩
+	assisted     bool      //这局AI是否走过 AI走过的局不进排行榜
+	recorded     bool      //这局是否已经记入排行榜和统计 每局只记一次
+	leaderboard  *leaderboard
+	settings     *settings
+	scenes       *sceneStack //场景栈 最底下是棋盘
+	play         *playScene  //棋盘的场景
 }
 
-func NewGame(screenWidth, screenHeight int) (*Game, error) {
+func NewGame(screenWidth, screenHeight int, options Options) (*Game, error) {
 	g := &Game{
 		ScreenWidth:  screenWidth,
 		ScreenHeight: screenHeight,
+		options:      options.withDefaults(),
 		input:        NewInput(),
+		autoplay:     options.Autoplay,
 	}
-	var err error
-	g.board, err = NewBoard(g.ScreenWidth, g.ScreenHeight, boardSize, tileSize, tileMargin)
+	g.undoButton = newActionButton("撤销", ActionUndo, g.undo)
+	g.redoButton = newActionButton("重做", ActionRedo, g.redo)
+	g.menuButton = newActionButton("菜单", ActionPause, g.openPause)
+	g.hintButton = newActionButton("提示", ActionHint, g.showHint)
+	g.play = &playScene{g: g}
+	g.scenes = newSceneStack(g.play)
+	leaderboard, err := loadLeaderboard()
 	if err != nil {
+		log.Printf("读取排行榜失败: %v", err)
+	}
+	g.leaderboard = leaderboard
+	settings, err := loadSettings()
+	if err != nil {
+		log.Printf("读取设置失败: %v", err)
+	}
+	g.settings = settings
+	settings.applyAccessibility()
+	g.input.SetKeymap(settings.keymap())
+	g.input.SetGamepadMaps(settings.Gamepads)
+	if err := g.loadTheme(); err != nil {
 		return g, err
 	}
-
+	//回放录像
+	if g.options.Replay != nil {
+		if err := g.startPlayback(g.options.Replay); err != nil {
+			return g, err
+		}
+		return g, nil
+	}
+	//有存档时继续上次的游戏 指定了棋盘、种子或者布局时直接开始新的一局
+	loaded := false
+	if !g.options.NoResume && g.options.Seed == 0 && len(g.options.Walls) == 0 {
+		loaded, err = g.load()
+		if err != nil {
+			log.Printf("读取存档失败，重新开始: %v", err)
+		}
+	}
+	if !loaded {
+		if err := g.loadBest(); err != nil {
+			log.Printf("读取最高分失败: %v", err)
+		}
+		if err := g.newGame(); err != nil {
+			return g, err
+		}
+	}
+	//标题画面盖在棋盘上，继续时直接回到棋盘
+	if !g.options.SkipTitle {
+		g.scenes.push(g.titleScene(loaded), transitionNone)
+	}
 	return g, nil
 }
 
+// newGame 重新开始一局
+func (g *Game) newGame() error {
+	//放弃没有结束的一局时也保存录像，结束的局和赢了之后放弃的局记入排行榜
+	//所有走过的局都记入统计，局数和胜率才是对的
+	if g.board != nil && g.playback == nil && !g.recorded {
+		state := g.board.State()
+		if state.CanMove() {
+			g.saveReplay()
+		}
+		if state.Reached(g.winTarget()) {
+			g.recordScore()
+		} else {
+			g.recordStats()
+		}
+	}
+	board, err := NewBoard(g.ScreenWidth, g.ScreenHeight, g.options.layout(), g.options.newSource())
+	if err != nil {
+		return err
+	}
+	board.SetUndoLimit(g.options.UndoLimit)
+	board.SetMoveQueue(g.options.MoveQueue, g.options.FastForward)
+	g.board = board
+	g.scenes.popAll(transitionFade)
+	g.keepPlaying = false
+	g.assisted = false
+	g.recorded = false
+	g.gainCount = 0
+	g.playback = nil
+	return nil
+}
+
+// saveReplay 保存这局的录像
+func (g *Game) saveReplay() {
+	path, err := g.board.writeReplay()
+	if err != nil {
+		log.Printf("保存录像失败: %v", err)
+		return
+	}
+	if path != "" {
+		log.Printf("录像已保存: %s", path)
+	}
+}
+
+// updateAutoplay 自动玩时棋盘停下来后在后台搜索，搜索完成后让AI走一步
+func (g *Game) updateAutoplay() error {
+	if !g.autoplay {
+		g.autoSearch = nil
+		return nil
+	}
+	if !g.board.IsSettled() {
+		return nil
+	}
+	//撤销或者开始新的一局后重新搜索
+	if g.autoSearch == nil || g.autoSearch.stale(g.board) {
+		g.autoSearch = startSearch(g.board, g.options.AI)
+		return nil
+	}
+	choices, ok := g.autoSearch.poll()
+	if !ok {
+		return nil
+	}
+	g.autoSearch = nil
+	dir, ok := ai.Best(choices)
+	if !ok {
+		return nil
+	}
+	g.assisted = true
+	return g.board.Move(dir)
+}
+
+// showHint 显示推荐的方向 再按一次隐藏或者取消正在计算的提示
+func (g *Game) showHint() error {
+	if _, ok := g.board.Hint(); ok {
+		g.board.ClearHint()
+		return nil
+	}
+	if g.hintWanted || g.hintSearch != nil {
+		g.hintWanted = false
+		g.hintSearch = nil
+		return nil
+	}
+	g.hintWanted = true
+	return nil
+}
+
+// winTarget 这局的胜利目标 没有设置时使用合并规则的目标
+func (g *Game) winTarget() int {
+	if 0 < g.options.WinTarget {
+		return g.options.WinTarget
+	}
+	return g.board.State().Rule().Target()
+}
+
+// undo 撤销上一步，关闭结束画面
+func (g *Game) undo() error {
+	if g.board.Undo() {
+		g.scenes.popAll(transitionFade)
+		g.gainCount = 0
+	}
+	return nil
+}
+
+// redo 重做上一次撤销的一步
+func (g *Game) redo() error {
+	g.board.Redo()
+	g.gainCount = 0
+	return nil
+}
+
+// checkEnd 棋盘停下来后判断是否赢了或者没有可以移动的方向
+func (g *Game) checkEnd() {
+	if g.scenes.top() != g.play || !g.board.IsSettled() {
+		return
+	}
+	state := g.board.State()
+	x, y := g.board.XY()
+	w, h := g.board.Size()
+	newGameButton := newActionButton("新游戏", ActionRestart, g.newGame)
+	switch {
+	case !g.keepPlaying && state.Reached(g.winTarget()):
+		//手柄的暂停键也可以继续
+		keepButton := newActionButton("继续游戏", ActionPause, func() error {
+			g.keepPlaying = true
+			return g.back()
+		})
+		keepButton.key = ebiten.KeyC
+		g.scenes.push(newOverlay("你赢了!", x, y, w, h, newGameButton, keepButton), transitionSlide)
+	case !state.CanMove():
+		buttons := []*button{newGameButton}
+		if g.board.CanUndo() {
+			buttons = append(buttons, newActionButton("撤销", ActionUndo, g.undo))
+		}
+		key := boardKey(state)
+		buttons = append(buttons, newButton("排行榜", ebiten.KeyL, func() error {
+			return g.open(g.leaderboardScene(key))
+		}))
+		var lines []string
+		//撤销后再次结束时不再保存录像和记录分数
+		if g.playback == nil && !g.recorded {
+			g.saveReplay()
+			if rank := g.recordScore(); 0 < rank {
+				lines = append(lines, "排行榜第"+strconv.Itoa(rank)+"名")
+			}
+			g.recorded = true
+		}
+		g.scenes.push(newTextOverlay("游戏结束", lines, x, y, w, h, buttons...), transitionSlide)
+	}
+}
+
 // Update
 // 更新更新游戏的逻辑状态
 // 每一tick都会调用这个函数,Tick是逻辑更新的时间单位。默认值为1/60[S]，则默认每秒调用60次更新(即一个Ebiten游戏每秒调用60次)。
@@ -38,10 +257,106 @@ func NewGame(screenWidth, screenHeight int) (*Game, error) {
 // 通常，当更新函数返回非零错误时，Ebiten游戏暂停。
 // 由于该程序从不返回非零错误，因此除非用户关闭窗口，否则Ebiten游戏永远不会停止。
 func (g *Game) Update() error {
+	//只有从棋盘开始的滑动才移动
+	bx, by := g.board.XY()
+	bw, bh := g.board.Size()
+	g.input.SetSwipeArea(bx, by, bw, bh)
 	g.input.Update()
-	if err := g.board.Update(g.input); err != nil {
+	if ebiten.IsWindowBeingClosed() {
+		return g.quit()
+	}
+	//只有最上面的场景接受输入
+	return g.scenes.Update(g.input)
+}
+
+// quit 保存游戏后退出 回放时不保存
+func (g *Game) quit() error {
+	if g.playback != nil {
+		return ebiten.Termination
+	}
+	g.board.finishTasks()
+	if err := g.save(); err != nil {
+		log.Printf("保存游戏失败: %v", err)
+	}
+	return ebiten.Termination
+}
+
+// updatePlay 更新棋盘和分数
+// input为nil时不接受输入，只播放棋盘的动画 例如切换场景的动画中
+func (g *Game) updatePlay(input *Input) error {
+	//回放时不接受玩家的移动，也不保存
+	if g.playback != nil {
+		if err := g.updatePlayback(input); err != nil {
+			return err
+		}
+		g.checkEnd()
+		return nil
+	}
+	g.updateHUD()
+	if input != nil {
+		if err := g.undoButton.Update(input); err != nil {
+			return err
+		}
+		if err := g.redoButton.Update(input); err != nil {
+			return err
+		}
+		if err := g.menuButton.Update(input); err != nil {
+			return err
+		}
+		if err := g.hintButton.Update(input); err != nil {
+			return err
+		}
+		//打开了菜单
+		if g.scenes.top() != g.play {
+			return nil
+		}
+		//切换自动玩
+		if input.Pressed(ActionAutoplay) {
+			g.autoplay = !g.autoplay
+		}
+		//放弃这局重新开始
+		if input.Pressed(ActionRestart) {
+			return g.newGame()
+		}
+		if err := g.updateAutoplay(); err != nil {
+			return err
+		}
+	}
+	score := g.board.Score()
+	if err := g.board.Update(input); err != nil {
 		return err
 	}
+	//这一帧有得分，显示得分提示
+	if gain := g.board.Score() - score; 0 < gain {
+		g.gain = gain
+		g.gainCount = maxGainCount
+	} else if 0 < g.gainCount {
+		g.gainCount--
+	}
+	//动画结束后再评估，这时棋盘上已经有新的格子
+	if g.hintWanted && g.board.IsSettled() {
+		g.hintWanted = false
+		g.hintSearch = startSearch(g.board, g.options.AI)
+	}
+	//搜索时走了一步的结果不再显示
+	if g.hintSearch != nil {
+		if g.hintSearch.stale(g.board) {
+			g.hintSearch = nil
+		} else if choices, ok := g.hintSearch.poll(); ok {
+			g.hintSearch = nil
+			g.board.SetHint(choices)
+		}
+	}
+	if g.best < g.board.Score() {
+		g.best = g.board.Score()
+	}
+	g.checkEnd()
+	//每走完一步自动保存
+	if g.board.IsSettled() && g.board.takeChanged() {
+		if err := g.save(); err != nil {
+			log.Printf("保存游戏失败: %v", err)
+		}
+	}
 	return nil
 }
 
@@ -53,7 +368,12 @@ func (g *Game) Update() error {
 // 屏幕参数是渲染的最终目的地。该窗口每帧显示屏幕的最终状态。
 func (g *Game) Draw(screen *ebiten.Image) {
 	//设置背景颜色
-	screen.Fill(backgroundColor)
+	screen.Fill(theme.Background)
+	g.scenes.Draw(screen)
+}
+
+// drawPlay 绘制棋盘和分数
+func (g *Game) drawPlay(screen *ebiten.Image) {
 	//渲染棋盘
 	g.board.Draw()
 	op := &ebiten.DrawImageOptions{}
@@ -62,8 +382,16 @@ func (g *Game) Draw(screen *ebiten.Image) {
 	x, y := g.board.XY()
 	op.GeoM.Translate(float64(x), float64(y))
 	screen.DrawImage(g.board.image, op)
+	//渲染分数
+	g.drawHUD(screen)
 }
 
 func (g *Game) Layout(outsideWidth, outsideHeight int) (screenWidth, screenHeight int) {
+	//窗口的大小是与设备无关的像素(dp)，画面缩放时滑动的阈值跟着缩放
+	//ebiten已经按DeviceScaleFactor把物理像素换算成dp，指针的位置是逻辑像素，
+	//所以阈值只按逻辑像素和dp的比例缩放，不用再乘DeviceScaleFactor
+	if 0 < outsideWidth {
+		g.input.SetScale(float64(g.ScreenWidth) / float64(outsideWidth))
+	}
 	return g.ScreenWidth, g.ScreenHeight
 }
@@ -0,0 +1,339 @@
+package core
+
+import (
+	"fmt"
+	"github.com/hajimehoshi/ebiten/v2"
+	"github.com/hajimehoshi/ebiten/v2/inpututil"
+	"log"
+	"math"
+	"strings"
+)
+
+const (
+	stickDeadzone  = 0.25 //摇杆在这个范围内算回到中间
+	stickThreshold = 0.6  //摇杆推过这个值时移动一步
+	flickCooldown  = 6    //摇杆回到中间后几帧内反方向的推动算回弹，不移动
+)
+
+// padButton 标准布局的手柄按钮 在设置中保存为名字
+type padButton ebiten.StandardGamepadButton
+
+// padButtonNames 按钮的名字 按Xbox手柄的叫法
+var padButtonNames = map[padButton]string{
+	padButton(ebiten.StandardGamepadButtonRightBottom):      "A",
+	padButton(ebiten.StandardGamepadButtonRightRight):       "B",
+	padButton(ebiten.StandardGamepadButtonRightLeft):        "X",
+	padButton(ebiten.StandardGamepadButtonRightTop):         "Y",
+	padButton(ebiten.StandardGamepadButtonFrontTopLeft):     "LB",
+	padButton(ebiten.StandardGamepadButtonFrontTopRight):    "RB",
+	padButton(ebiten.StandardGamepadButtonFrontBottomLeft):  "LT",
+	padButton(ebiten.StandardGamepadButtonFrontBottomRight): "RT",
+	padButton(ebiten.StandardGamepadButtonCenterLeft):       "Back",
+	padButton(ebiten.StandardGamepadButtonCenterRight):      "Start",
+	padButton(ebiten.StandardGamepadButtonCenterCenter):     "Home",
+	padButton(ebiten.StandardGamepadButtonLeftStick):        "LS",
+	padButton(ebiten.StandardGamepadButtonRightStick):       "RS",
+	padButton(ebiten.StandardGamepadButtonLeftTop):          "Up",
+	padButton(ebiten.StandardGamepadButtonLeftRight):        "Right",
+	padButton(ebiten.StandardGamepadButtonLeftBottom):       "Down",
+	padButton(ebiten.StandardGamepadButtonLeftLeft):         "Left",
+}
+
+// String 按钮的名字
+func (b padButton) String() string {
+	if name, ok := padButtonNames[b]; ok {
+		return name
+	}
+	return fmt.Sprintf("Button(%d)", int(b))
+}
+
+// MarshalText 编码成按钮的名字
+func (b padButton) MarshalText() ([]byte, error) {
+	name, ok := padButtonNames[b]
+	if !ok {
+		return nil, fmt.Errorf("twenty48: invalid gamepad button %d", int(b))
+	}
+	return []byte(name), nil
+}
+
+// UnmarshalText 解析按钮的名字
+func (b *padButton) UnmarshalText(text []byte) error {
+	for button, name := range padButtonNames {
+		if name == string(text) {
+			*b = button
+			return nil
+		}
+	}
+	return fmt.Errorf("twenty48: unknown gamepad button %q", text)
+}
+
+// GamepadMap 每个操作绑定的手柄按钮 摇杆固定用来移动
+type GamepadMap map[Action][]padButton
+
+// defaultGamepadMap 默认的手柄按钮
+// 十字键移动，A撤销，B重做，X提示，Y重新开始，Start暂停，Back切换自动玩
+func defaultGamepadMap() GamepadMap {
+	return GamepadMap{
+		ActionUp:       {padButton(ebiten.StandardGamepadButtonLeftTop)},
+		ActionRight:    {padButton(ebiten.StandardGamepadButtonLeftRight)},
+		ActionDown:     {padButton(ebiten.StandardGamepadButtonLeftBottom)},
+		ActionLeft:     {padButton(ebiten.StandardGamepadButtonLeftLeft)},
+		ActionUndo:     {padButton(ebiten.StandardGamepadButtonRightBottom)},
+		ActionRedo:     {padButton(ebiten.StandardGamepadButtonRightRight)},
+		ActionHint:     {padButton(ebiten.StandardGamepadButtonRightLeft)},
+		ActionRestart:  {padButton(ebiten.StandardGamepadButtonRightTop)},
+		ActionPause:    {padButton(ebiten.StandardGamepadButtonCenterRight)},
+		ActionAutoplay: {padButton(ebiten.StandardGamepadButtonCenterLeft)},
+	}
+}
+
+// clone 复制一份 修改时不影响原来的
+func (m GamepadMap) clone() GamepadMap {
+	c := GamepadMap{}
+	for a, buttons := range m {
+		c[a] = append([]padButton(nil), buttons...)
+	}
+	return c
+}
+
+// pressed 这一帧手柄id是否刚按下了a绑定的按钮
+func (m GamepadMap) pressed(id ebiten.GamepadID, a Action) bool {
+	for _, b := range m[a] {
+		if inpututil.IsStandardGamepadButtonJustPressed(id, ebiten.StandardGamepadButton(b)) {
+			return true
+		}
+	}
+	return false
+}
+
+// bind 只把button绑定到a 这个按钮原来绑定的其他操作会解除绑定
+func (m GamepadMap) bind(a Action, button padButton) {
+	for other, buttons := range m {
+		for i, b := range buttons {
+			if b == button {
+				m[other] = append(buttons[:i:i], buttons[i+1:]...)
+				break
+			}
+		}
+	}
+	m[a] = []padButton{button}
+}
+
+// buttonsLabel a绑定的按钮 例如"A RB"
+func (m GamepadMap) buttonsLabel(a Action) string {
+	if len(m[a]) == 0 {
+		return "-"
+	}
+	names := make([]string, len(m[a]))
+	for i, b := range m[a] {
+		names[i] = b.String()
+	}
+	return strings.Join(names, " ")
+}
+
+// gamepad 一个连接着的手柄
+type gamepad struct {
+	id   ebiten.GamepadID
+	guid string //SDL的id 同一型号的手柄一样，用来保存绑定
+	name string
+
+	stickArmed bool //摇杆回到中间后才能再移动
+	stickIdle  int  //摇杆回到中间后经过的帧数
+	lastDir    Dir  //摇杆上一次移动的方向
+}
+
+// stickDir 摇杆推过阈值时的方向 每次推动只移动一步
+// 推完松开时摇杆会弹到反方向，回到中间后很快出现的反方向推动不算
+func (p *gamepad) stickDir() (Dir, bool) {
+	x := ebiten.StandardGamepadAxisValue(p.id, ebiten.StandardGamepadAxisLeftStickHorizontal)
+	y := ebiten.StandardGamepadAxisValue(p.id, ebiten.StandardGamepadAxisLeftStickVertical)
+	l := math.Hypot(x, y)
+	if l < stickDeadzone {
+		if !p.stickArmed {
+			p.stickArmed = true
+			p.stickIdle = 0
+		}
+		p.stickIdle++
+		return 0, false
+	}
+	if !p.stickArmed || l < stickThreshold {
+		return 0, false
+	}
+	var d Dir
+	switch {
+	case math.Abs(y) < math.Abs(x) && x < 0:
+		d = DirLeft
+	case math.Abs(y) < math.Abs(x):
+		d = DirRight
+	case y < 0:
+		d = DirUp
+	default:
+		d = DirDown
+	}
+	p.stickArmed = false
+	if p.stickIdle < flickCooldown && d == oppositeDir(p.lastDir) {
+		return 0, false
+	}
+	p.lastDir = d
+	return d, true
+}
+
+// oppositeDir 相反的方向
+func oppositeDir(d Dir) Dir {
+	switch d {
+	case DirUp:
+		return DirDown
+	case DirRight:
+		return DirLeft
+	case DirDown:
+		return DirUp
+	case DirLeft:
+		return DirRight
+	}
+	panic("not reach")
+}
+
+// updateGamepads 处理手柄的连接和断开，记录这一帧手柄按下的操作
+func (i *Input) updateGamepads() {
+	i.padActions = [actionCount]bool{}
+	i.padDirOK = false
+	for _, id := range inpututil.AppendJustConnectedGamepadIDs(nil) {
+		p := &gamepad{
+			id:   id,
+			guid: ebiten.GamepadSDLID(id),
+			name: ebiten.GamepadName(id),
+		}
+		if !ebiten.IsStandardGamepadLayoutAvailable(id) {
+			log.Printf("手柄%s没有标准布局，不能使用", p.name)
+			continue
+		}
+		log.Printf("手柄已连接: %s", p.name)
+		i.gamepads[id] = p
+	}
+	for id, p := range i.gamepads {
+		if inpututil.IsGamepadJustDisconnected(id) {
+			log.Printf("手柄已断开: %s", p.name)
+			delete(i.gamepads, id)
+			continue
+		}
+		m := i.GamepadMap(p.guid)
+		for a := Action(0); a < actionCount; a++ {
+			if m.pressed(id, a) {
+				i.padActions[a] = true
+				i.lastPad = p
+			}
+		}
+		if d, ok := p.stickDir(); ok && !i.padDirOK {
+			i.padDir, i.padDirOK = d, true
+			i.lastPad = p
+		}
+	}
+	if i.lastPad != nil {
+		if _, ok := i.gamepads[i.lastPad.id]; !ok {
+			i.lastPad = nil
+		}
+	}
+}
+
+// SetGamepadMaps 改用新的手柄绑定 键为手柄的SDL id，没有的手柄使用默认的绑定
+func (i *Input) SetGamepadMaps(m map[string]GamepadMap) {
+	i.gamepadMaps = m
+}
+
+// GamepadMap 手柄guid的按钮绑定
+func (i *Input) GamepadMap(guid string) GamepadMap {
+	if m, ok := i.gamepadMaps[guid]; ok {
+		return m
+	}
+	return defaultGamepadMap()
+}
+
+// Gamepad 最近用过的手柄 没有时返回任意一个连接着的手柄，都没有时返回nil
+func (i *Input) Gamepad() *gamepad {
+	if i.lastPad != nil {
+		return i.lastPad
+	}
+	for _, p := range i.gamepads {
+		return p
+	}
+	return nil
+}
+
+// gamepadSettingsScene 最近用过的手柄的按钮设置画面 绑定按型号保存
+func (g *Game) gamepadSettingsScene(message string) *overlay {
+	p := g.input.Gamepad()
+	back := newBackButton("返回", g.back)
+	back.w = hudBoxWidth
+	if p == nil {
+		return newTextOverlay("手柄", []string{"没有连接手柄"}, 0, 0, g.ScreenWidth, g.ScreenHeight, back)
+	}
+	m := g.input.GamepadMap(p.guid)
+	lines := []string{shorten(p.name, 20), "摇杆\t移动"}
+	for a := Action(0); a < actionCount; a += 2 {
+		lines = append(lines, fmt.Sprintf("%s\t%s\t%s\t%s",
+			actionLabels[a], m.buttonsLabel(a), actionLabels[a+1], m.buttonsLabel(a+1)))
+	}
+	if message != "" {
+		lines = append(lines, message)
+	}
+	var buttons []*button
+	for a := Action(0); a < actionCount; a++ {
+		a := a
+		b := newButton(actionLabels[a], -1, func() error {
+			return g.refresh(g.gamepadRebindScene(p, a))
+		})
+		b.w = sizeButtonWidth
+		buttons = append(buttons, b)
+	}
+	reset := newButton("重置", -1, func() error {
+		delete(g.settings.Gamepads, p.guid)
+		g.saveGamepadMaps()
+		return g.refresh(g.gamepadSettingsScene("已恢复默认按钮"))
+	})
+	reset.w = hudBoxWidth
+	buttons = append(buttons, reset, back)
+	return newTextOverlay("手柄", lines, 0, 0, g.ScreenWidth, g.ScreenHeight, buttons...)
+}
+
+// gamepadRebindScene 等待手柄p按下a的新按钮 Esc取消
+func (g *Game) gamepadRebindScene(p *gamepad, a Action) *overlay {
+	lines := []string{
+		"当前\t" + g.input.GamepadMap(p.guid).buttonsLabel(a),
+		"在手柄上按下新的按钮",
+	}
+	cancel := newButton("取消", ebiten.KeyEscape, func() error {
+		return g.refresh(g.gamepadSettingsScene(""))
+	})
+	cancel.fixed = true
+	o := newTextOverlay(actionLabels[a], lines, 0, 0, g.ScreenWidth, g.ScreenHeight, cancel)
+	o.onUpdate = func() error {
+		if _, ok := g.input.gamepads[p.id]; !ok {
+			return g.refresh(g.gamepadSettingsScene("手柄已断开"))
+		}
+		pressed := inpututil.AppendJustPressedStandardGamepadButtons(p.id, nil)
+		if len(pressed) == 0 {
+			return nil
+		}
+		button := padButton(pressed[0])
+		if _, ok := padButtonNames[button]; !ok {
+			return nil
+		}
+		m := g.input.GamepadMap(p.guid).clone()
+		m.bind(a, button)
+		if g.settings.Gamepads == nil {
+			g.settings.Gamepads = map[string]GamepadMap{}
+		}
+		g.settings.Gamepads[p.guid] = m
+		g.saveGamepadMaps()
+		return g.refresh(g.gamepadSettingsScene(""))
+	}
+	return o
+}
+
+// saveGamepadMaps 使用并保存设置中的手柄绑定
+func (g *Game) saveGamepadMaps() {
+	g.input.SetGamepadMaps(g.settings.Gamepads)
+	if err := g.settings.save(); err != nil {
+		log.Printf("保存设置失败: %v", err)
+	}
+}
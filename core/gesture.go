@@ -0,0 +1,217 @@
+package core
+
+import (
+	"math"
+	"time"
+)
+
+// gestureKind 识别出的手势
+type gestureKind int
+
+const (
+	gestureNone   gestureKind = iota //还没有结果
+	gestureSwipe                     //滑动 移动一步
+	gestureTap                       //点击 按下后几乎没有移动就松开
+	gestureCancel                    //这次按下不再识别 离开了棋盘或者方向不明确
+)
+
+// gestureEvent 识别的结果
+type gestureEvent struct {
+	kind gestureKind
+	dir  Dir //滑动的方向
+	x    int //点击的位置
+	y    int //点击的位置
+}
+
+// gestureConfig 识别手势的阈值 距离的单位是与设备无关的像素(dp)
+type gestureConfig struct {
+	swipeDistance  float64 //按着滑过这个距离时马上移动，不用松开
+	flickDistance  float64 //没滑到swipeDistance就松开时，速度够快也算滑动
+	flickVelocity  float64 //松开前的速度超过这个值算快速滑动 每秒多少dp
+	tapSlop        float64 //移动不超过这个距离算点击
+	angleTolerance float64 //偏离水平或竖直方向不超过这个角度才算滑动 单位是度
+	velocityWindow time.Duration
+}
+
+// defaultGestureConfig 默认的阈值
+var defaultGestureConfig = gestureConfig{
+	swipeDistance:  24,
+	flickDistance:  10,
+	flickVelocity:  300,
+	tapSlop:        6,
+	angleTolerance: 30,
+	velocityWindow: 100 * time.Millisecond,
+}
+
+// gestureSample 指针的一次采样
+type gestureSample struct {
+	x float64
+	y float64
+	t time.Duration
+}
+
+// gestureRecognizer 把一个指针按下、移动和松开的过程识别为滑动或者点击
+// 只依赖传入的坐标和时间，可以用合成的轨迹驱动
+//
+//	r.Down(x, y, t)    按下
+//	r.Move(x, y, t)    按着移动 滑过阈值时返回滑动
+//	r.Up(x, y, t)      松开 返回快速滑动或者点击
+//	r.Cancel()         放弃这次按下 例如多个手指触摸
+type gestureRecognizer struct {
+	config gestureConfig
+	scale  float64 //每dp多少逻辑像素
+
+	//只识别从这个区域开始的滑动 离开区域时取消 w为0时不限制
+	areaX, areaY, areaW, areaH int
+
+	down    bool
+	inArea  bool //按下的位置在区域内
+	done    bool //这次按下已经滑动或者取消了
+	start   gestureSample
+	history []gestureSample //最近velocityWindow内的采样 计算松开时的速度
+}
+
+// newGestureRecognizer 使用config的阈值
+func newGestureRecognizer(config gestureConfig) *gestureRecognizer {
+	return &gestureRecognizer{config: config, scale: 1}
+}
+
+// SetScale 设置每dp多少逻辑像素 画面缩放时阈值跟着缩放
+func (r *gestureRecognizer) SetScale(scale float64) {
+	if scale <= 0 {
+		scale = 1
+	}
+	r.scale = scale
+}
+
+// SetArea 只识别从这个区域开始的滑动
+func (r *gestureRecognizer) SetArea(x, y, w, h int) {
+	r.areaX, r.areaY, r.areaW, r.areaH = x, y, w, h
+}
+
+// contains 点是否在区域内
+func (r *gestureRecognizer) contains(x, y float64) bool {
+	if r.areaW == 0 {
+		return true
+	}
+	return float64(r.areaX) <= x && x <= float64(r.areaX+r.areaW) && float64(r.areaY) <= y && y <= float64(r.areaY+r.areaH)
+}
+
+// Down 指针在(x,y)按下
+func (r *gestureRecognizer) Down(x, y int, t time.Duration) {
+	s := gestureSample{x: float64(x), y: float64(y), t: t}
+	r.down = true
+	r.done = false
+	r.inArea = r.contains(s.x, s.y)
+	r.start = s
+	r.history = append(r.history[:0], s)
+}
+
+// Move 指针按着移动到(x,y) 滑过阈值时返回滑动，离开区域时返回取消
+func (r *gestureRecognizer) Move(x, y int, t time.Duration) gestureEvent {
+	if !r.down || r.done {
+		return gestureEvent{}
+	}
+	s := r.record(x, y, t)
+	if !r.inArea {
+		return gestureEvent{}
+	}
+	if !r.contains(s.x, s.y) {
+		r.done = true
+		return gestureEvent{kind: gestureCancel}
+	}
+	dx, dy := s.x-r.start.x, s.y-r.start.y
+	dist := math.Hypot(dx, dy)
+	if dist < r.config.swipeDistance*r.scale {
+		return gestureEvent{}
+	}
+	if d, ok := r.axisDir(dx, dy); ok {
+		r.done = true
+		return gestureEvent{kind: gestureSwipe, dir: d}
+	}
+	//斜着滑了很远还没有明确的方向，这次不算
+	if 2*r.config.swipeDistance*r.scale <= dist {
+		r.done = true
+		return gestureEvent{kind: gestureCancel}
+	}
+	return gestureEvent{}
+}
+
+// Up 指针在(x,y)松开 返回快速滑动或者点击
+func (r *gestureRecognizer) Up(x, y int, t time.Duration) gestureEvent {
+	if !r.down {
+		return gestureEvent{}
+	}
+	if r.done {
+		r.down = false
+		return gestureEvent{}
+	}
+	if e := r.Move(x, y, t); e.kind != gestureNone {
+		r.down = false
+		return e
+	}
+	r.down = false
+	if r.done {
+		return gestureEvent{}
+	}
+	s := r.history[len(r.history)-1]
+	dx, dy := s.x-r.start.x, s.y-r.start.y
+	dist := math.Hypot(dx, dy)
+	if dist < r.config.tapSlop*r.scale {
+		return gestureEvent{kind: gestureTap, x: x, y: y}
+	}
+	if !r.inArea || dist < r.config.flickDistance*r.scale || r.velocity() < r.config.flickVelocity*r.scale {
+		return gestureEvent{}
+	}
+	if d, ok := r.axisDir(dx, dy); ok {
+		return gestureEvent{kind: gestureSwipe, dir: d}
+	}
+	return gestureEvent{}
+}
+
+// Cancel 放弃这次按下 松开前不再识别
+func (r *gestureRecognizer) Cancel() {
+	r.done = true
+}
+
+// record 记录采样，去掉超过velocityWindow的旧采样 至少保留一个旧采样用来计算速度
+func (r *gestureRecognizer) record(x, y int, t time.Duration) gestureSample {
+	s := gestureSample{x: float64(x), y: float64(y), t: t}
+	r.history = append(r.history, s)
+	n := 0
+	for n < len(r.history)-2 && r.config.velocityWindow < t-r.history[n+1].t {
+		n++
+	}
+	r.history = append(r.history[:0], r.history[n:]...)
+	return s
+}
+
+// velocity 最近velocityWindow内的平均速度 每秒多少逻辑像素
+func (r *gestureRecognizer) velocity() float64 {
+	first, last := r.history[0], r.history[len(r.history)-1]
+	dt := (last.t - first.t).Seconds()
+	if dt <= 0 {
+		return 0
+	}
+	return math.Hypot(last.x-first.x, last.y-first.y) / dt
+}
+
+// axisDir 位移接近水平或竖直方向时的方向
+func (r *gestureRecognizer) axisDir(dx, dy float64) (Dir, bool) {
+	major, minor := math.Abs(dx), math.Abs(dy)
+	if major < minor {
+		major, minor = minor, major
+	}
+	if r.config.angleTolerance < math.Atan2(minor, major)*180/math.Pi {
+		return 0, false
+	}
+	switch {
+	case math.Abs(dy) < math.Abs(dx) && dx < 0:
+		return DirLeft, true
+	case math.Abs(dy) < math.Abs(dx):
+		return DirRight, true
+	case dy < 0:
+		return DirUp, true
+	}
+	return DirDown, true
+}
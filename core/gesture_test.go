@@ -0,0 +1,144 @@
+package core
+
+import (
+	"testing"
+	"time"
+)
+
+// ms 毫秒
+func ms(n int) time.Duration {
+	return time.Duration(n) * time.Millisecond
+}
+
+func TestGestureSwipeBeforeRelease(t *testing.T) {
+	r := newGestureRecognizer(defaultGestureConfig)
+	r.Down(100, 100, 0)
+	if e := r.Move(110, 100, ms(16)); e.kind != gestureNone {
+		t.Fatalf("move below threshold = %+v, want none", e)
+	}
+	e := r.Move(126, 100, ms(32))
+	if e.kind != gestureSwipe || e.dir != DirRight {
+		t.Fatalf("move past threshold = %+v, want swipe right", e)
+	}
+	//一次按下只移动一步
+	if e := r.Move(200, 100, ms(48)); e.kind != gestureNone {
+		t.Errorf("move after swipe = %+v, want none", e)
+	}
+	if e := r.Up(200, 100, ms(64)); e.kind != gestureNone {
+		t.Errorf("up after swipe = %+v, want none", e)
+	}
+}
+
+func TestGestureFlickOnRelease(t *testing.T) {
+	r := newGestureRecognizer(defaultGestureConfig)
+	r.Down(100, 100, 0)
+	if e := r.Move(100, 95, ms(16)); e.kind != gestureNone {
+		t.Fatalf("move = %+v, want none", e)
+	}
+	e := r.Up(100, 88, ms(32))
+	if e.kind != gestureSwipe || e.dir != DirUp {
+		t.Fatalf("fast release = %+v, want swipe up", e)
+	}
+
+	//同样的距离松开得太慢不算
+	r.Down(100, 100, ms(1000))
+	r.Move(100, 95, ms(1300))
+	if e := r.Up(100, 88, ms(1600)); e.kind != gestureNone {
+		t.Errorf("slow release = %+v, want none", e)
+	}
+}
+
+func TestGestureTap(t *testing.T) {
+	r := newGestureRecognizer(defaultGestureConfig)
+	r.Down(50, 50, 0)
+	r.Move(52, 51, ms(50))
+	e := r.Up(52, 51, ms(100))
+	if e.kind != gestureTap || e.x != 52 || e.y != 51 {
+		t.Fatalf("tap = %+v, want tap at (52,51)", e)
+	}
+}
+
+func TestGestureDiagonalRejected(t *testing.T) {
+	r := newGestureRecognizer(defaultGestureConfig)
+	r.Down(100, 100, 0)
+	if e := r.Move(120, 120, ms(16)); e.kind != gestureNone {
+		t.Fatalf("diagonal move = %+v, want none", e)
+	}
+	if e := r.Move(140, 140, ms(32)); e.kind != gestureCancel {
+		t.Fatalf("long diagonal move = %+v, want cancel", e)
+	}
+	if e := r.Up(140, 140, ms(48)); e.kind != gestureNone {
+		t.Errorf("up after cancel = %+v, want none", e)
+	}
+
+	//快速斜着松开也不算
+	r.Down(100, 100, ms(1000))
+	if e := r.Up(110, 110, ms(1016)); e.kind != gestureNone {
+		t.Errorf("diagonal flick = %+v, want none", e)
+	}
+
+	//偏离不超过角度容差时算滑动
+	r.Down(100, 100, ms(2000))
+	if e := r.Move(75, 110, ms(2016)); e.kind != gestureSwipe || e.dir != DirLeft {
+		t.Errorf("slightly tilted move = %+v, want swipe left", e)
+	}
+}
+
+func TestGestureLeavesArea(t *testing.T) {
+	r := newGestureRecognizer(defaultGestureConfig)
+	r.SetArea(0, 0, 200, 200)
+	r.Down(190, 100, 0)
+	if e := r.Move(205, 100, ms(16)); e.kind != gestureCancel {
+		t.Fatalf("leaving the board = %+v, want cancel", e)
+	}
+	if e := r.Up(230, 100, ms(32)); e.kind != gestureNone {
+		t.Errorf("up after leaving = %+v, want none", e)
+	}
+
+	//从棋盘外开始的滑动不移动
+	r.Down(250, 100, ms(1000))
+	if e := r.Move(300, 100, ms(1016)); e.kind != gestureNone {
+		t.Errorf("swipe outside the board = %+v, want none", e)
+	}
+	if e := r.Up(300, 100, ms(1032)); e.kind != gestureNone {
+		t.Errorf("release outside the board = %+v, want none", e)
+	}
+}
+
+func TestGestureScale(t *testing.T) {
+	//每dp两个逻辑像素 阈值是48像素
+	r := newGestureRecognizer(defaultGestureConfig)
+	r.SetScale(2)
+	r.Down(100, 100, 0)
+	if e := r.Move(100, 130, ms(16)); e.kind != gestureNone {
+		t.Fatalf("30px at scale 2 = %+v, want none", e)
+	}
+	if e := r.Move(100, 150, ms(32)); e.kind != gestureSwipe || e.dir != DirDown {
+		t.Fatalf("50px at scale 2 = %+v, want swipe down", e)
+	}
+
+	//10像素在缩放后还在点击的范围内
+	r.Down(100, 100, ms(1000))
+	if e := r.Up(110, 100, ms(1100)); e.kind != gestureTap {
+		t.Errorf("10px release at scale 2 = %+v, want tap", e)
+	}
+
+	//缩放为0时按1处理
+	r.SetScale(0)
+	r.Down(100, 100, ms(2000))
+	if e := r.Move(125, 100, ms(2016)); e.kind != gestureSwipe {
+		t.Errorf("25px at scale 1 = %+v, want swipe", e)
+	}
+}
+
+func TestGestureCancel(t *testing.T) {
+	r := newGestureRecognizer(defaultGestureConfig)
+	r.Down(100, 100, 0)
+	r.Cancel()
+	if e := r.Move(150, 100, ms(16)); e.kind != gestureNone {
+		t.Errorf("move after cancel = %+v, want none", e)
+	}
+	if e := r.Up(150, 100, ms(32)); e.kind != gestureNone {
+		t.Errorf("up after cancel = %+v, want none", e)
+	}
+}
@@ -1,6 +1,7 @@
 package core
 
 import (
+	"gameTest/engine"
 	"gameTest/fonts"
 	"github.com/hajimehoshi/ebiten/v2"
 	"github.com/hajimehoshi/ebiten/v2/text"
@@ -12,6 +13,7 @@ import (
 )
 
 var (
+	mplusTinyFont   font.Face //最小的字体
 	mplusSmallFont  font.Face //小字体
 	mplusNormalFont font.Face //中等字体
 	mplusBigFont    font.Face //大字体
@@ -22,51 +24,67 @@ const (
 	maxPoppingCount = 6
 )
 const (
-	tileSize   = 80 //每个格子的宽高
-	tileMargin = 4  //每个格子之间的间距
+	maxTileSize = 80 //每个格子最大的宽高
+	tileMargin  = 4  //每个格子之间的间距
 )
 
 var (
-	gridImage = ebiten.NewImage(tileSize, tileSize)
+	shangshouFont *opentype.Font           //格子和界面使用的字体
+	tileFontCache = map[int][3]font.Face{} //不同大小的格子使用的字体
 )
 
-// 初始化格子的动画
-func init() {
-	gridImage.Fill(color.White)
-}
-
 // 初始化字体
 func init() {
 	tt, err := opentype.Parse(fonts.Shangshou)
 	if err != nil {
 		log.Fatal(err)
 	}
+	if err := setFont(tt); err != nil {
+		log.Fatal(err)
+	}
+}
 
+// setFont 格子和界面改用tt字体 重新生成所有大小的字体
+func setFont(tt *opentype.Font) error {
 	const dpi = 72
-	mplusSmallFont, err = opentype.NewFace(tt, &opentype.FaceOptions{
-		Size:    24,
-		DPI:     dpi,
-		Hinting: font.HintingVertical,
-	})
-	if err != nil {
-		log.Fatal(err)
+	var faces [4]font.Face
+	for i, size := range []float64{16, 24, 32, 48} {
+		f, err := opentype.NewFace(tt, &opentype.FaceOptions{
+			Size:    size,
+			DPI:     dpi,
+			Hinting: font.HintingVertical,
+		})
+		if err != nil {
+			return err
+		}
+		faces[i] = f
 	}
-	mplusNormalFont, err = opentype.NewFace(tt, &opentype.FaceOptions{
-		Size:    32,
-		DPI:     dpi,
-		Hinting: font.HintingVertical,
-	})
-	if err != nil {
-		log.Fatal(err)
+	shangshouFont = tt
+	mplusTinyFont, mplusSmallFont, mplusNormalFont, mplusBigFont = faces[0], faces[1], faces[2], faces[3]
+	tileFontCache = map[int][3]font.Face{}
+	return nil
+}
+
+// tileFonts 格子大小对应的大、中、小字体
+// 以80的格子使用48、32、24的字体为准按比例缩放
+func tileFonts(tileSize int) [3]font.Face {
+	if fs, ok := tileFontCache[tileSize]; ok {
+		return fs
 	}
-	mplusBigFont, err = opentype.NewFace(tt, &opentype.FaceOptions{
-		Size:    48,
-		DPI:     dpi,
-		Hinting: font.HintingVertical,
-	})
-	if err != nil {
-		log.Fatal(err)
+	var fs [3]font.Face
+	for i, size := range []float64{48, 32, 24} {
+		f, err := opentype.NewFace(shangshouFont, &opentype.FaceOptions{
+			Size:    size * float64(tileSize) / maxTileSize,
+			DPI:     72,
+			Hinting: font.HintingVertical,
+		})
+		if err != nil {
+			log.Fatal(err)
+		}
+		fs[i] = f
 	}
+	tileFontCache[tileSize] = fs
+	return fs
 }
 
 type Grid struct {
@@ -164,7 +182,8 @@ func (t *Grid) Update() error {
 }
 
 // Draw 将当前格子绘制到给定的boardImage。
-func (t *Grid) Draw(boardImage *ebiten.Image) {
+// tileSize和tileMargin是棋盘上格子的大小和间距，rule决定格子的颜色
+func (t *Grid) Draw(boardImage *ebiten.Image, tileSize, tileMargin int, rule engine.MergeRule) {
 	//获取当前格子的位置
 	i, j := t.current.x, t.current.y
 	//获取移动后的位置
@@ -216,20 +235,32 @@ func (t *Grid) Draw(boardImage *ebiten.Image) {
 		op.GeoM.Translate(float64(tileSize/2), float64(tileSize/2))
 	}
 	op.GeoM.Translate(float64(x), float64(y))
-	op.ColorScale.ScaleWithColor(gridBackgroundColor(v))
-	boardImage.DrawImage(gridImage, op)
+	//按值在规则数列中的位置取主题或者配色的颜色
+	rank := tileRank(rule, v)
+	bg, fg := tileColors(rank)
+	//高对比度时先画描边，格子缩小到描边里面
+	inset := 0
+	if access.highContrast {
+		inset = outlineWidth(tileSize)
+		drawInset(boardImage, tileImage(tileSize), op, 0, contrastColor(theme.Board))
+	}
+	drawInset(boardImage, tileImage(tileSize-2*inset), op, inset, bg)
+	if access.patterns {
+		drawInset(boardImage, patternImage(tileSize-2*inset, rank), op, inset, withAlpha(fg, patternAlpha))
+	}
 	//格子中的值转换为字符串
 	str := strconv.Itoa(v)
 
-	f := mplusBigFont
+	fs := tileFonts(tileSize)
+	f := fs[0]
 	//值长度超过2用普通字体
 	//值长度超过3用小字体
 	//其余使用大字体
 	switch {
 	case 3 < len(str):
-		f = mplusSmallFont
+		f = fs[2]
 	case 2 < len(str):
-		f = mplusNormalFont
+		f = fs[1]
 	}
 	//计算字体的位置
 	w := font.MeasureString(f, str).Floor()
@@ -237,7 +268,22 @@ func (t *Grid) Draw(boardImage *ebiten.Image) {
 	//居中
 	x += (tileSize - w) / 2
 	y += (tileSize-h)/2 + f.Metrics().Ascent.Floor()
-	text.Draw(boardImage, str, f, x, y, gridColor(v))
+	text.Draw(boardImage, str, f, x, y, fg)
+	//高对比度时错开几个像素再画几次，数字更粗
+	if access.highContrast {
+		for d := 1; d <= boldOffset(tileSize); d++ {
+			text.Draw(boardImage, str, f, x+d, y, fg)
+		}
+	}
+}
+
+// drawInset 用op的变换在格子中缩进inset绘制img，img用clr上色
+func drawInset(dst, img *ebiten.Image, op *ebiten.DrawImageOptions, inset int, clr color.Color) {
+	o := &ebiten.DrawImageOptions{}
+	o.GeoM.Translate(float64(inset), float64(inset))
+	o.GeoM.Concat(op.GeoM)
+	o.ColorScale.ScaleWithColor(clr)
+	dst.DrawImage(img, o)
 }
 
 // mean 计算a移动到b,走过rate后的值
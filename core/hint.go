@@ -0,0 +1,100 @@
+package core
+
+import (
+	"gameTest/ai"
+	"github.com/hajimehoshi/ebiten/v2/vector"
+	"strconv"
+)
+
+const (
+	hintLabelWidth  = 64 //方向分数框的宽
+	hintLabelHeight = 24 //方向分数框的高
+)
+
+// hint 提示 推荐的方向和每个方向的评估
+type hint struct {
+	choices [4]ai.Choice //按engine.Dirs的顺序
+	best    Dir          //推荐的方向
+	ok      bool         //是否有可以移动的方向
+}
+
+// SetHint 显示提示 choices为ai.Evaluate的结果
+// 评估只用棋盘的规则状态，不会改变格子和动画
+func (b *Board) SetHint(choices [4]ai.Choice) {
+	best, ok := ai.Best(choices)
+	b.hint = &hint{
+		choices: choices,
+		best:    best,
+		ok:      ok,
+	}
+}
+
+// Hint 当前显示的提示 没有提示时返回false
+func (b *Board) Hint() ([4]ai.Choice, bool) {
+	if b.hint == nil {
+		return [4]ai.Choice{}, false
+	}
+	return b.hint.choices, true
+}
+
+// ClearHint 隐藏提示
+func (b *Board) ClearHint() {
+	b.hint = nil
+}
+
+// drawHint 在棋盘中间画推荐方向的箭头，四边显示每个方向的分数
+func (b *Board) drawHint() {
+	h := b.hint
+	if h.ok {
+		b.drawArrow(h.best)
+	}
+	for _, c := range h.choices {
+		str := "-"
+		if c.Valid {
+			str = strconv.FormatFloat(c.Score, 'f', 1, 64)
+		}
+		clr := withAlpha(theme.Title, 0xc0)
+		if h.ok && c.Valid && c.Dir == h.best {
+			clr = withAlpha(theme.Accent, 0xe0)
+		}
+		//分数框贴着棋盘对应的边
+		x := (b.w - hintLabelWidth) / 2
+		y := (b.h - hintLabelHeight) / 2
+		switch c.Dir {
+		case DirUp:
+			y = b.tileMargin
+		case DirRight:
+			x = b.w - hintLabelWidth - b.tileMargin
+		case DirDown:
+			y = b.h - hintLabelHeight - b.tileMargin
+		case DirLeft:
+			x = b.tileMargin
+		}
+		vector.DrawFilledRect(b.image, float32(x), float32(y), hintLabelWidth, hintLabelHeight, clr, false)
+		drawTextCenter(b.image, str, mplusTinyFont, x, y, hintLabelWidth, hintLabelHeight, theme.Value)
+	}
+}
+
+// drawArrow 在棋盘中间画dir方向的箭头
+func (b *Board) drawArrow(dir Dir) {
+	dx, dy := dir.Vector()
+	cx, cy := float32(b.w)/2, float32(b.h)/2
+	//箭头的长度为棋盘短边的1/3
+	l := float32(b.w)
+	if float32(b.h) < l {
+		l = float32(b.h)
+	}
+	l /= 3
+	width := float32(b.tileSize) / 6
+	//箭头从尾部指向头部
+	tx, ty := cx-float32(dx)*l/2, cy-float32(dy)*l/2
+	hx, hy := cx+float32(dx)*l/2, cy+float32(dy)*l/2
+	vector.StrokeLine(b.image, tx, ty, hx, hy, width, theme.Accent, true)
+	//头部的两边向后斜45度
+	head := l / 3
+	for _, side := range []float32{-1, 1} {
+		ex := hx - float32(dx)*head + float32(dy)*head*side
+		ey := hy - float32(dy)*head + float32(dx)*head*side
+		vector.StrokeLine(b.image, hx, hy, ex, ey, width, theme.Accent, true)
+	}
+}
@@ -0,0 +1,159 @@
+package core
+
+import (
+	"gameTest/engine"
+)
+
+const (
+	maxHistory = 100 //最多保存几步撤销记录
+)
+
+// snapshot 撤销记录中的一步
+// 保存的是棋盘停下来时的状态，包括新增的格子、分数和随机数的状态
+type snapshot struct {
+	state engine.State
+	rng   uint64 //随机数的状态
+	moves int    //已经走了几步
+
+	undone *engine.ReplayMove //重做记录中被撤销的那一步，重做时加回录像
+}
+
+// history 可以撤销和重做的记录
+type history struct {
+	undo  []snapshot //可以撤销的记录 最后一个是最近的
+	redo  []snapshot //可以重做的记录 最后一个是最近撤销的
+	limit int        //每局最多撤销几次 0为不限制
+	used  int        //这局已经撤销了几次
+}
+
+// push 记录一步 新的移动会清空重做记录
+func (h *history) push(s snapshot) {
+	h.undo = append(h.undo, s)
+	//超过上限的丢掉最早的记录
+	if len(h.undo) > maxHistory {
+		h.undo = h.undo[len(h.undo)-maxHistory:]
+	}
+	h.redo = h.redo[:0]
+}
+
+// canUndo 是否还可以撤销
+func (h *history) canUndo() bool {
+	if 0 < h.limit && h.limit <= h.used {
+		return false
+	}
+	return 0 < len(h.undo)
+}
+
+// canRedo 是否还可以重做
+func (h *history) canRedo() bool {
+	return 0 < len(h.redo)
+}
+
+// remaining 还可以撤销几次 不限制时为-1
+func (h *history) remaining() int {
+	if h.limit <= 0 {
+		return -1
+	}
+	return h.limit - h.used
+}
+
+// CanUndo 是否还可以撤销
+func (b *Board) CanUndo() bool {
+	return b.history.canUndo()
+}
+
+// CanRedo 是否还可以重做
+func (b *Board) CanRedo() bool {
+	return b.history.canRedo()
+}
+
+// UndosLeft 这局还可以撤销几次 不限制时为-1
+func (b *Board) UndosLeft() int {
+	return b.history.remaining()
+}
+
+// SetUndoLimit 设置每局最多撤销几次 0为不限制
+func (b *Board) SetUndoLimit(limit int) {
+	b.history.limit = limit
+}
+
+// Undo 撤销上一步
+// 动画进行中时先把这一步走完再撤销
+func (b *Board) Undo() bool {
+	b.finishTasks()
+	if !b.history.canUndo() {
+		return false
+	}
+	h := &b.history
+	prev := h.undo[len(h.undo)-1]
+	h.undo = h.undo[:len(h.undo)-1]
+	cur := b.snapshot()
+	//撤销的那一步从录像中去掉，重做时再加回来
+	if b.record != nil && 0 < len(b.record.Moves) {
+		m := b.record.Moves[len(b.record.Moves)-1]
+		cur.undone = &m
+	}
+	h.redo = append(h.redo, cur)
+	h.used++
+	b.restore(prev)
+	return true
+}
+
+// Redo 重做上一次撤销的一步
+func (b *Board) Redo() bool {
+	b.finishTasks()
+	if !b.history.canRedo() {
+		return false
+	}
+	h := &b.history
+	next := h.redo[len(h.redo)-1]
+	h.redo = h.redo[:len(h.redo)-1]
+	h.undo = append(h.undo, b.snapshot())
+	b.restore(next)
+	if b.record != nil && next.undone != nil {
+		b.record.Moves = append(b.record.Moves, *next.undone)
+	}
+	return true
+}
+
+// snapshot 当前棋盘的记录
+func (b *Board) snapshot() snapshot {
+	return snapshot{
+		state: b.state,
+		rng:   b.rng.State(),
+		moves: b.moves,
+	}
+}
+
+// restore 恢复到记录中的状态，重新生成格子
+func (b *Board) restore(s snapshot) {
+	b.state = s.state
+	b.rng.SetState(s.rng)
+	b.moves = s.moves
+	//录像只保留走到这里的部分
+	if b.record != nil && s.moves < len(b.record.Moves) {
+		b.record.Moves = b.record.Moves[:s.moves]
+	}
+	b.last = engine.MoveResult{}
+	b.changed = true
+	b.hint = nil
+	b.ClearQueue()
+	//恢复的格子不需要弹出的动画
+	b.grids = newGrids(b.state.Tiles(), false)
+}
+
+// finishTasks 立即结束进行中的动画，并执行完所有任务
+func (b *Board) finishTasks() {
+	for len(b.tasks) > 0 {
+		for t := range b.grids {
+			t.stopAnimation()
+		}
+		t := b.tasks[0]
+		if err := t(); err == taskTerminated {
+			b.tasks = b.tasks[1:]
+		} else if err != nil {
+			//任务出错时放弃剩下的任务
+			b.tasks = nil
+		}
+	}
+}
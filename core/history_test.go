@@ -0,0 +1,150 @@
+package core
+
+import (
+	"gameTest/engine"
+	"reflect"
+	"testing"
+)
+
+// testBoard 4x4的经典棋盘
+func testBoard(t *testing.T, seed uint64) *Board {
+	t.Helper()
+	b, err := NewBoard(420, 600, engine.New(4, 4), engine.NewSource(seed))
+	if err != nil {
+		t.Fatal(err)
+	}
+	return b
+}
+
+// step 走能走的一步并走完动画
+func step(t *testing.T, b *Board) {
+	t.Helper()
+	moves := b.Moves()
+	for _, d := range engine.Dirs {
+		if err := b.Move(d); err != nil {
+			t.Fatal(err)
+		}
+		if b.Moves() != moves {
+			b.finishTasks()
+			return
+		}
+	}
+	t.Fatal("the board cannot move")
+}
+
+func TestUndoRedo(t *testing.T) {
+	b := testBoard(t, 1)
+	var states []snapshot
+	for i := 0; i < 5; i++ {
+		states = append(states, b.snapshot())
+		step(t, b)
+	}
+	final := b.snapshot()
+	finalMoves := append([]engine.ReplayMove(nil), b.Replay().Moves...)
+
+	for i := len(states) - 1; 0 <= i; i-- {
+		if !b.Undo() {
+			t.Fatalf("Undo #%d failed", i)
+		}
+		if got := b.snapshot(); !sameSnapshot(got, states[i]) {
+			t.Fatalf("after undo to %d moves: %+v, want %+v", i, got, states[i])
+		}
+		if len(b.Replay().Moves) != i {
+			t.Errorf("replay has %d moves after undo, want %d", len(b.Replay().Moves), i)
+		}
+	}
+	if b.CanUndo() || b.Undo() {
+		t.Errorf("undo at the start of the game")
+	}
+
+	for b.CanRedo() {
+		b.Redo()
+	}
+	if got := b.snapshot(); !sameSnapshot(got, final) {
+		t.Fatalf("after redo: %+v, want %+v", got, final)
+	}
+	if !reflect.DeepEqual(b.Replay().Moves, finalMoves) {
+		t.Errorf("replay after redo = %v, want %v", b.Replay().Moves, finalMoves)
+	}
+	if len(b.grids) != len(b.State().Tiles()) {
+		t.Errorf("%d grids for %d tiles", len(b.grids), len(b.State().Tiles()))
+	}
+}
+
+func TestMoveClearsRedo(t *testing.T) {
+	b := testBoard(t, 2)
+	step(t, b)
+	step(t, b)
+	b.Undo()
+	if !b.CanRedo() {
+		t.Fatal("nothing to redo after undo")
+	}
+	step(t, b)
+	if b.CanRedo() || b.Redo() {
+		t.Errorf("redo after a new move")
+	}
+}
+
+func TestHistoryBound(t *testing.T) {
+	var h history
+	for i := 0; i < maxHistory+10; i++ {
+		h.push(snapshot{moves: i})
+	}
+	if len(h.undo) != maxHistory {
+		t.Fatalf("%d snapshots, want %d", len(h.undo), maxHistory)
+	}
+	//最早的记录被丢掉
+	if h.undo[0].moves != 10 || h.undo[maxHistory-1].moves != maxHistory+9 {
+		t.Errorf("kept moves %d..%d", h.undo[0].moves, h.undo[maxHistory-1].moves)
+	}
+}
+
+func TestUndoLimit(t *testing.T) {
+	b := testBoard(t, 3)
+	b.SetUndoLimit(2)
+	for i := 0; i < 4; i++ {
+		step(t, b)
+	}
+	if got := b.UndosLeft(); got != 2 {
+		t.Fatalf("UndosLeft = %d, want 2", got)
+	}
+	if !b.Undo() || !b.Undo() {
+		t.Fatal("undo within the limit failed")
+	}
+	if b.UndosLeft() != 0 || b.CanUndo() || b.Undo() {
+		t.Errorf("undo past the limit")
+	}
+	//重做不会退回已经用掉的次数
+	b.Redo()
+	if b.CanUndo() {
+		t.Errorf("redo gave back an undo")
+	}
+	b.SetUndoLimit(0)
+	if b.UndosLeft() != -1 || !b.Undo() {
+		t.Errorf("undo without a limit failed")
+	}
+}
+
+func TestRestoreClearsQueue(t *testing.T) {
+	b := testBoard(t, 4)
+	b.SetMoveQueue(4, false)
+	step(t, b)
+	step(t, b)
+	b.queue.push(DirUp)
+	b.queue.push(DirLeft)
+	b.Undo()
+	if got := b.QueuedMoves(); len(got) != 0 {
+		t.Errorf("queue after undo = %v", got)
+	}
+	if b.Moves() != 1 || len(b.Replay().Moves) != 1 {
+		t.Errorf("after undo: %d moves, replay has %d", b.Moves(), len(b.Replay().Moves))
+	}
+	if b.LastMove().Moved {
+		t.Errorf("last move kept after undo")
+	}
+}
+
+// sameSnapshot 两个记录的棋盘、随机数和步数是否一样
+func sameSnapshot(a, b snapshot) bool {
+	return sameState(a.state, b.state) && a.rng == b.rng && a.moves == b.moves
+}
@@ -0,0 +1,126 @@
+package core
+
+import (
+	"github.com/hajimehoshi/ebiten/v2"
+	"github.com/hajimehoshi/ebiten/v2/text"
+	"golang.org/x/image/font"
+	"image/color"
+	"strconv"
+)
+
+const (
+	hudBoxWidth  = 100 //分数框的宽
+	hudBoxHeight = 60  //分数框的高
+	hudMargin    = 10  //分数框之间的距离
+	maxGainCount = 40  //得分提示显示几帧
+	hudHeight    = 180 //棋盘上方留给HUD的高度
+	hudMinWidth  = 400 //HUD最小的宽度 棋盘比它窄时HUD按这个宽度居中
+)
+
+var (
+	hudBoxImage = ebiten.NewImage(hudBoxWidth, hudBoxHeight)
+)
+
+// 初始化分数框
+func init() {
+	hudBoxImage.Fill(color.White)
+}
+
+// hudArea HUD的左边位置和宽度 和棋盘对齐，棋盘太窄时按hudMinWidth居中
+func (g *Game) hudArea() (int, int) {
+	x, _ := g.board.XY()
+	w, _ := g.board.Size()
+	if w < hudMinWidth {
+		w = hudMinWidth
+		x = (g.ScreenWidth - w) / 2
+	}
+	return x, w
+}
+
+// updateHUD 更新菜单、撤销和重做按钮的位置和状态
+func (g *Game) updateHUD() {
+	x, w := g.hudArea()
+	_, y := g.board.XY()
+	//按钮在分数框上方，菜单和提示左对齐，撤销和重做右对齐
+	//四个按钮放不下时按宽度平分
+	by := y - hudBoxHeight - buttonHeight - 2*hudMargin
+	bw := hudBoxWidth
+	if n := (w - 3*hudMargin) / 4; n < bw {
+		bw = n
+	}
+	for i, b := range []*button{g.menuButton, g.hintButton} {
+		b.w = bw
+		b.setXY(x+i*(bw+hudMargin), by)
+	}
+	for i, b := range []*button{g.redoButton, g.undoButton} {
+		b.w = bw
+		b.setXY(x+w-(i+1)*bw-i*hudMargin, by)
+	}
+
+	g.undoButton.label = "撤销"
+	if n := g.board.UndosLeft(); 0 <= n {
+		g.undoButton.label = "撤销(" + strconv.Itoa(n) + ")"
+	}
+	g.undoButton.disabled = !g.board.CanUndo()
+	g.redoButton.disabled = !g.board.CanRedo()
+}
+
+// drawHUD 在棋盘上方的空白区域绘制标题、当前分数和最高分
+func (g *Game) drawHUD(screen *ebiten.Image) {
+	x, w := g.hudArea()
+	_, y := g.board.XY()
+	//分数框在棋盘上方，右对齐
+	top := y - hudBoxHeight - hudMargin
+	bestX := x + w - hudBoxWidth
+	scoreX := bestX - hudBoxWidth - hudMargin
+
+	//标题
+	m := mplusBigFont.Metrics()
+	text.Draw(screen, strconv.Itoa(g.winTarget()), mplusBigFont, x, top+(hudBoxHeight-(m.Ascent+m.Descent).Floor())/2+m.Ascent.Floor(), theme.Title)
+
+	//随机种子，用来复现这一局 回放时显示回放的进度
+	info := "种子 " + strconv.FormatUint(g.board.Seed(), 10)
+	if g.playback != nil {
+		info = g.playback.status(g.board)
+	}
+	if g.autoplay {
+		info += " 自动"
+	}
+	text.Draw(screen, info, mplusTinyFont, x, hudMargin+mplusTinyFont.Metrics().Ascent.Floor(), theme.Title)
+
+	drawScoreBox(screen, "分数", g.board.Score(), scoreX, top)
+	drawScoreBox(screen, "最高", g.best, bestX, top)
+	if g.playback == nil {
+		g.menuButton.Draw(screen)
+		g.hintButton.Draw(screen)
+		g.undoButton.Draw(screen)
+		g.redoButton.Draw(screen)
+	}
+
+	//刚得到的分数，从分数框往上飘
+	if 0 < g.gainCount {
+		rate := 1 - float64(g.gainCount)/maxGainCount
+		gy := mean(top, top-hudBoxHeight, rate)
+		drawTextCenter(screen, "+"+strconv.Itoa(g.gain), mplusSmallFont, scoreX, gy, hudBoxWidth, hudBoxHeight/2, theme.Title)
+	}
+}
+
+// drawScoreBox 绘制带标题的分数框
+func drawScoreBox(screen *ebiten.Image, label string, value int, x, y int) {
+	op := &ebiten.DrawImageOptions{}
+	op.GeoM.Translate(float64(x), float64(y))
+	op.ColorScale.ScaleWithColor(theme.Frame)
+	screen.DrawImage(hudBoxImage, op)
+	//上半部分是标题，下半部分是分数
+	drawTextCenter(screen, label, mplusSmallFont, x, y, hudBoxWidth, hudBoxHeight/2, theme.Label)
+	drawTextCenter(screen, strconv.Itoa(value), mplusSmallFont, x, y+hudBoxHeight/2, hudBoxWidth, hudBoxHeight/2, theme.Value)
+}
+
+// drawTextCenter 在矩形区域内居中绘制文字
+func drawTextCenter(dst *ebiten.Image, str string, f font.Face, x, y, width, height int, clr color.Color) {
+	w := font.MeasureString(f, str).Floor()
+	h := (f.Metrics().Ascent + f.Metrics().Descent).Floor()
+	x += (width - w) / 2
+	y += (height-h)/2 + f.Metrics().Ascent.Floor()
+	text.Draw(dst, str, f, x, y, clr)
+}
@@ -3,143 +3,176 @@ package core
 import (
 	"github.com/hajimehoshi/ebiten/v2"
 	"github.com/hajimehoshi/ebiten/v2/inpututil"
+	"time"
 )
 
 // Input represents the current key states.
 type Input struct {
-	//鼠标状态
-	mouseState mouseState
-	//鼠标位置的x轴
-	mouseInitPosX int
-	//鼠标位置的y轴
-	mouseInitPosY int
-	//鼠标移动方向
-	mouseDir Dir
-
-	//触摸
-	touches       []ebiten.TouchID
-	touchState    touchState
-	touchID       ebiten.TouchID
-	touchInitPosX int
-	touchInitPosY int
-	touchLastPosX int
-	touchLastPosY int
-	touchDir      Dir
+	start time.Time //用来计算采样的时间
+
+	//鼠标和触摸各用一个识别器 触摸只跟踪一个手指
+	mouse    *gestureRecognizer
+	touch    *gestureRecognizer
+	touchID  ebiten.TouchID
+	touching bool
+	touches  []ebiten.TouchID
+	swipeDir Dir //这一帧滑动的方向
+	swipeOK  bool
+
+	//点击 按下后没有滑动就松开
+	tapped bool
+	tapX   int
+	tapY   int
+
+	//按键绑定
+	keymap Keymap
+
+	//手柄
+	gamepads    map[ebiten.GamepadID]*gamepad //连接着的标准布局的手柄
+	gamepadMaps map[string]GamepadMap         //每种手柄的按钮绑定 键为SDL id
+	lastPad     *gamepad                      //最近用过的手柄
+	padActions  [actionCount]bool             //这一帧手柄按下的操作
+	padDir      Dir                           //这一帧摇杆的方向
+	padDirOK    bool
 }
 
 // NewInput generates a new Input object.
 func NewInput() *Input {
-	return &Input{}
+	return &Input{
+		start:    time.Now(),
+		mouse:    newGestureRecognizer(defaultGestureConfig),
+		touch:    newGestureRecognizer(defaultGestureConfig),
+		keymap:   defaultKeymap(),
+		gamepads: map[ebiten.GamepadID]*gamepad{},
+	}
+}
+
+// SetSwipeArea 只识别从这个区域开始的滑动 一般为棋盘
+func (i *Input) SetSwipeArea(x, y, width, height int) {
+	i.mouse.SetArea(x, y, width, height)
+	i.touch.SetArea(x, y, width, height)
+}
+
+// SetScale 设置每个与设备无关的像素是多少逻辑像素 滑动的阈值按这个缩放
+func (i *Input) SetScale(scale float64) {
+	i.mouse.SetScale(scale)
+	i.touch.SetScale(scale)
+}
+
+// SetKeymap 改用新的按键绑定
+func (i *Input) SetKeymap(m Keymap) {
+	i.keymap = m
+}
+
+// Keymap 当前的按键绑定
+func (i *Input) Keymap() Keymap {
+	return i.keymap
+}
+
+// Pressed 这一帧是否刚按下了a绑定的按键或者手柄按钮
+func (i *Input) Pressed(a Action) bool {
+	return i.keymap.Pressed(a) || i.padActions[a]
 }
 
 // Update updates the current input states.
 func (i *Input) Update() {
-	//根据鼠标的状态改变动画
-	switch i.mouseState {
-	case mouseStateNone: //空状态
-		if ebiten.IsMouseButtonPressed(ebiten.MouseButtonLeft) {
-			x, y := ebiten.CursorPosition()
-			i.mouseInitPosX = x
-			i.mouseInitPosY = y
-			i.mouseState = mouseStatePressing
-		}
-	case mouseStatePressing:
-		if !ebiten.IsMouseButtonPressed(ebiten.MouseButtonLeft) {
-			x, y := ebiten.CursorPosition()
-			dx := x - i.mouseInitPosX
-			dy := y - i.mouseInitPosY
-			d, ok := vecToDir(dx, dy)
-			if !ok {
-				i.mouseState = mouseStateNone
-				break
-			}
-			i.mouseDir = d
-			i.mouseState = mouseStateSettled
-		}
-	case mouseStateSettled:
-		i.mouseState = mouseStateNone
+	i.tapped = false
+	i.swipeOK = false
+	i.updateGamepads()
+	now := time.Since(i.start)
+
+	//鼠标
+	x, y := ebiten.CursorPosition()
+	switch {
+	case inpututil.IsMouseButtonJustPressed(ebiten.MouseButtonLeft):
+		i.mouse.Down(x, y, now)
+	case inpututil.IsMouseButtonJustReleased(ebiten.MouseButtonLeft):
+		i.handleGesture(i.mouse.Up(x, y, now))
+	case ebiten.IsMouseButtonPressed(ebiten.MouseButtonLeft):
+		i.handleGesture(i.mouse.Move(x, y, now))
 	}
 
-	//根据触摸来改编动画
+	//触摸 只跟踪第一个手指，有第二个手指时这次不算
 	i.touches = ebiten.AppendTouchIDs(i.touches[:0])
-	switch i.touchState {
-	case touchStateNone:
-		if len(i.touches) == 1 {
-			i.touchID = i.touches[0]
-			x, y := ebiten.TouchPosition(i.touches[0])
-			i.touchInitPosX = x
-			i.touchInitPosY = y
-			i.touchLastPosX = x
-			i.touchLastPosX = y
-			i.touchState = touchStatePressing
-		}
-	case touchStatePressing:
-		if len(i.touches) >= 2 {
-			break
-		}
-		if len(i.touches) == 1 {
-			if i.touches[0] != i.touchID {
-				i.touchState = touchStateInvalid
-			} else {
-				x, y := ebiten.TouchPosition(i.touches[0])
-				i.touchLastPosX = x
-				i.touchLastPosY = y
-			}
-			break
-		}
-		if len(i.touches) == 0 {
-			dx := i.touchLastPosX - i.touchInitPosX
-			dy := i.touchLastPosY - i.touchInitPosY
-			d, ok := vecToDir(dx, dy)
-			if !ok {
-				i.touchState = touchStateNone
-				break
+	if i.touching {
+		if inpututil.IsTouchJustReleased(i.touchID) {
+			tx, ty := inpututil.TouchPositionInPreviousTick(i.touchID)
+			i.handleGesture(i.touch.Up(tx, ty, now))
+			i.touching = false
+		} else {
+			tx, ty := ebiten.TouchPosition(i.touchID)
+			i.handleGesture(i.touch.Move(tx, ty, now))
+			if 1 < len(i.touches) {
+				i.touch.Cancel()
 			}
-			i.touchDir = d
-			i.touchState = touchStateSettled
 		}
-	case touchStateSettled:
-		i.touchState = touchStateNone
-	case touchStateInvalid:
-		if len(i.touches) == 0 {
-			i.touchState = touchStateNone
+	}
+	if !i.touching {
+		if ids := inpututil.AppendJustPressedTouchIDs(nil); len(ids) == 1 && len(i.touches) == 1 {
+			i.touchID = ids[0]
+			i.touching = true
+			tx, ty := ebiten.TouchPosition(i.touchID)
+			i.touch.Down(tx, ty, now)
 		}
 	}
 }
 
+// handleGesture 记录识别出的滑动或者点击
+func (i *Input) handleGesture(e gestureEvent) {
+	switch e.kind {
+	case gestureSwipe:
+		i.swipeDir = e.dir
+		i.swipeOK = true
+	case gestureTap:
+		i.setTap(e.x, e.y)
+	}
+}
+
 // Dir returns a currently pressed direction.
 // Dir returns false if no direction key is pressed.
 func (i *Input) Dir() (Dir, bool) {
-	if inpututil.IsKeyJustPressed(ebiten.KeyArrowUp) {
-		return DirUp, true
-	}
-	if inpututil.IsKeyJustPressed(ebiten.KeyArrowLeft) {
-		return DirLeft, true
-	}
-	if inpututil.IsKeyJustPressed(ebiten.KeyArrowRight) {
-		return DirRight, true
-	}
-	if inpututil.IsKeyJustPressed(ebiten.KeyArrowDown) {
-		return DirDown, true
+	if d, ok := i.ButtonDir(); ok {
+		return d, true
 	}
-	if i.mouseState == mouseStateSettled {
-		return i.mouseDir, true
+	return i.SwipeDir()
+}
+
+// ButtonDir 这一帧按键、手柄按钮或者摇杆的方向
+func (i *Input) ButtonDir() (Dir, bool) {
+	for _, a := range []Action{ActionUp, ActionLeft, ActionRight, ActionDown} {
+		if i.Pressed(a) {
+			return moveActions[a], true
+		}
 	}
-	if i.touchState == touchStateSettled {
-		return i.touchDir, true
+	if i.padDirOK {
+		return i.padDir, true
 	}
 	return 0, false
 }
 
-func (i *Input) InTheArea(x, y, width, height int) bool {
-	inArea := false
-	if i.mouseInitPosX >= x && i.mouseInitPosX <= x+width && i.mouseInitPosY >= y && i.mouseInitPosY <= y+height {
-		inArea = true
-	}
-	if i.touchLastPosX >= x && i.touchLastPosX <= x+width && i.touchLastPosY >= y && i.touchLastPosY <= y+height {
-		inArea = true
-	}
+// SwipeDir 这一帧鼠标或者触摸滑动的方向 只有从棋盘开始的滑动
+func (i *Input) SwipeDir() (Dir, bool) {
+	return i.swipeDir, i.swipeOK
+}
 
-	return inArea
+// setTap 记录一次点击
+func (i *Input) setTap(x, y int) {
+	i.tapped = true
+	i.tapX = x
+	i.tapY = y
+}
 
+// Tap 这一帧的点击位置
+// 没有点击时返回false
+func (i *Input) Tap() (int, int, bool) {
+	return i.tapX, i.tapY, i.tapped
+}
+
+// TappedIn 这一帧是否点击了该区域
+func (i *Input) TappedIn(x, y, width, height int) bool {
+	tx, ty, ok := i.Tap()
+	if !ok {
+		return false
+	}
+	return tx >= x && tx <= x+width && ty >= y && ty <= y+height
 }
@@ -0,0 +1,222 @@
+package core
+
+import (
+	"fmt"
+	"github.com/hajimehoshi/ebiten/v2"
+	"github.com/hajimehoshi/ebiten/v2/inpututil"
+	"strings"
+)
+
+// Action 可以绑定按键的操作
+type Action int
+
+const (
+	ActionUp       Action = iota //向上移动
+	ActionRight                  //向右移动
+	ActionDown                   //向下移动
+	ActionLeft                   //向左移动
+	ActionUndo                   //撤销
+	ActionRedo                   //重做
+	ActionRestart                //重新开始
+	ActionHint                   //提示
+	ActionPause                  //暂停 打开菜单
+	ActionAutoplay               //切换自动玩
+	actionCount
+)
+
+// actionNames 操作保存在设置中的名字
+var actionNames = [actionCount]string{"up", "right", "down", "left", "undo", "redo", "restart", "hint", "pause", "autoplay"}
+
+// actionLabels 操作在界面上显示的名字
+var actionLabels = [actionCount]string{"上", "右", "下", "左", "撤销", "重做", "重开", "提示", "暂停", "自动"}
+
+// moveActions 移动的操作和方向
+var moveActions = map[Action]Dir{
+	ActionUp:    DirUp,
+	ActionRight: DirRight,
+	ActionDown:  DirDown,
+	ActionLeft:  DirLeft,
+}
+
+// String 操作的名字
+func (a Action) String() string {
+	if a < 0 || actionCount <= a {
+		return fmt.Sprintf("Action(%d)", int(a))
+	}
+	return actionNames[a]
+}
+
+// MarshalText 编码成操作的名字
+func (a Action) MarshalText() ([]byte, error) {
+	if a < 0 || actionCount <= a {
+		return nil, fmt.Errorf("twenty48: invalid action %d", int(a))
+	}
+	return []byte(actionNames[a]), nil
+}
+
+// UnmarshalText 解析操作的名字
+func (a *Action) UnmarshalText(text []byte) error {
+	for i, name := range actionNames {
+		if name == string(text) {
+			*a = Action(i)
+			return nil
+		}
+	}
+	return fmt.Errorf("twenty48: unknown action %q", text)
+}
+
+// Keymap 每个操作绑定的按键 一个操作可以绑定多个按键
+type Keymap map[Action][]ebiten.Key
+
+// clone 复制一份 修改时不影响原来的
+func (m Keymap) clone() Keymap {
+	c := Keymap{}
+	for a, keys := range m {
+		c[a] = append([]ebiten.Key(nil), keys...)
+	}
+	return c
+}
+
+// Pressed 这一帧是否刚按下了a绑定的按键
+func (m Keymap) Pressed(a Action) bool {
+	for _, k := range m[a] {
+		if inpututil.IsKeyJustPressed(k) {
+			return true
+		}
+	}
+	return false
+}
+
+// Bind 把key绑定到a 这个按键原来绑定的其他操作会解除绑定
+func (m Keymap) Bind(a Action, key ebiten.Key) {
+	for other, keys := range m {
+		for i, k := range keys {
+			if k == key && other != a {
+				m[other] = append(keys[:i:i], keys[i+1:]...)
+				break
+			}
+		}
+	}
+	for _, k := range m[a] {
+		if k == key {
+			return
+		}
+	}
+	m[a] = append(m[a], key)
+}
+
+// action 绑定了key的操作 没有绑定时返回false
+func (m Keymap) action(key ebiten.Key) (Action, bool) {
+	for a, keys := range m {
+		for _, k := range keys {
+			if k == key {
+				return a, true
+			}
+		}
+	}
+	return 0, false
+}
+
+// Unbind 解除a所有的按键
+func (m Keymap) Unbind(a Action) {
+	delete(m, a)
+}
+
+// keysLabel a绑定的按键 例如"ArrowUp W"
+func (m Keymap) keysLabel(a Action) string {
+	if len(m[a]) == 0 {
+		return "-"
+	}
+	names := make([]string, len(m[a]))
+	for i, k := range m[a] {
+		names[i] = strings.TrimPrefix(k.String(), "Arrow")
+	}
+	return strings.Join(names, " ")
+}
+
+// keyScheme 预设的按键方案
+type keyScheme struct {
+	name   string
+	label  string //设置中显示的名字
+	keymap Keymap
+}
+
+// customScheme 玩家自己绑定的方案的名字
+const customScheme = "custom"
+
+// defaultKeymap 默认的方向键方案
+func defaultKeymap() Keymap {
+	return Keymap{
+		ActionUp:       {ebiten.KeyArrowUp},
+		ActionRight:    {ebiten.KeyArrowRight},
+		ActionDown:     {ebiten.KeyArrowDown},
+		ActionLeft:     {ebiten.KeyArrowLeft},
+		ActionUndo:     {ebiten.KeyZ},
+		ActionRedo:     {ebiten.KeyY},
+		ActionRestart:  {ebiten.KeyN},
+		ActionHint:     {ebiten.KeyH},
+		ActionPause:    {ebiten.KeyEscape},
+		ActionAutoplay: {ebiten.KeyA},
+	}
+}
+
+// keySchemes 可以选择的按键方案 方向键一直可以用
+var keySchemes = []keyScheme{
+	{name: "arrows", label: "方向键", keymap: defaultKeymap()},
+	{name: "wasd", label: "WASD", keymap: func() Keymap {
+		//左手在WASD上，自动玩改为T
+		m := defaultKeymap()
+		m.Bind(ActionUp, ebiten.KeyW)
+		m.Bind(ActionRight, ebiten.KeyD)
+		m.Bind(ActionDown, ebiten.KeyS)
+		m.Bind(ActionLeft, ebiten.KeyA)
+		m.Bind(ActionAutoplay, ebiten.KeyT)
+		m.Bind(ActionUndo, ebiten.KeyQ)
+		m.Bind(ActionRedo, ebiten.KeyE)
+		return m
+	}()},
+	{name: "vim", label: "hjkl", keymap: func() Keymap {
+		//和vim一样 u撤销，提示改为/
+		m := defaultKeymap()
+		m.Bind(ActionUp, ebiten.KeyK)
+		m.Bind(ActionRight, ebiten.KeyL)
+		m.Bind(ActionDown, ebiten.KeyJ)
+		m.Bind(ActionLeft, ebiten.KeyH)
+		m.Bind(ActionHint, ebiten.KeySlash)
+		m.Bind(ActionUndo, ebiten.KeyU)
+		m.Bind(ActionRedo, ebiten.KeyR)
+		return m
+	}()},
+	{name: "numpad", label: "小键盘", keymap: func() Keymap {
+		m := defaultKeymap()
+		m.Bind(ActionUp, ebiten.KeyNumpad8)
+		m.Bind(ActionRight, ebiten.KeyNumpad6)
+		m.Bind(ActionDown, ebiten.KeyNumpad2)
+		m.Bind(ActionLeft, ebiten.KeyNumpad4)
+		m.Bind(ActionUndo, ebiten.KeyNumpad0)
+		m.Bind(ActionRedo, ebiten.KeyNumpadDecimal)
+		m.Bind(ActionHint, ebiten.KeyNumpadEnter)
+		m.Bind(ActionRestart, ebiten.KeyNumpadSubtract)
+		m.Bind(ActionPause, ebiten.KeyNumpadMultiply)
+		m.Bind(ActionAutoplay, ebiten.KeyNumpadAdd)
+		return m
+	}()},
+}
+
+// keySchemeByName 根据名字找到预设的方案 没有时返回false
+func keySchemeByName(name string) (keyScheme, bool) {
+	for _, s := range keySchemes {
+		if s.name == name {
+			return s, true
+		}
+	}
+	return keyScheme{}, false
+}
+
+// keySchemeLabel 方案显示的名字
+func keySchemeLabel(name string) string {
+	if s, ok := keySchemeByName(name); ok {
+		return s.label
+	}
+	return "自定义"
+}
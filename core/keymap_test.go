@@ -0,0 +1,110 @@
+package core
+
+import (
+	"github.com/hajimehoshi/ebiten/v2"
+	"reflect"
+	"testing"
+)
+
+func TestKeymapBind(t *testing.T) {
+	m := defaultKeymap()
+	m.Bind(ActionUndo, ebiten.KeyU)
+	if got := m[ActionUndo]; !reflect.DeepEqual(got, []ebiten.Key{ebiten.KeyZ, ebiten.KeyU}) {
+		t.Errorf("undo keys = %v", got)
+	}
+	//同一个按键只绑定一次
+	m.Bind(ActionUndo, ebiten.KeyU)
+	if len(m[ActionUndo]) != 2 {
+		t.Errorf("undo keys = %v after binding twice", m[ActionUndo])
+	}
+	//绑定到新的操作时从原来的操作解除
+	m.Bind(ActionRedo, ebiten.KeyZ)
+	if got := m[ActionUndo]; !reflect.DeepEqual(got, []ebiten.Key{ebiten.KeyU}) {
+		t.Errorf("undo keys = %v after moving Z to redo", got)
+	}
+	if a, ok := m.action(ebiten.KeyZ); !ok || a != ActionRedo {
+		t.Errorf("action(Z) = %v, %v, want redo", a, ok)
+	}
+	m.Unbind(ActionRedo)
+	if _, ok := m.action(ebiten.KeyZ); ok {
+		t.Errorf("Z is still bound after Unbind")
+	}
+	//修改复制的绑定不影响原来的
+	c := m.clone()
+	c.Bind(ActionHint, ebiten.KeyU)
+	if a, _ := m.action(ebiten.KeyU); a != ActionUndo {
+		t.Errorf("clone changed the original")
+	}
+}
+
+func TestKeySchemes(t *testing.T) {
+	for _, s := range keySchemes {
+		t.Run(s.name, func(t *testing.T) {
+			seen := map[ebiten.Key]Action{}
+			for a := Action(0); a < actionCount; a++ {
+				if len(s.keymap[a]) == 0 {
+					t.Errorf("%v has no key", a)
+				}
+				for _, k := range s.keymap[a] {
+					if other, ok := seen[k]; ok {
+						t.Errorf("%v is bound to %v and %v", k, other, a)
+					}
+					seen[k] = a
+				}
+			}
+			//方向键一直可以用
+			for a, k := range map[Action]ebiten.Key{
+				ActionUp: ebiten.KeyArrowUp, ActionRight: ebiten.KeyArrowRight,
+				ActionDown: ebiten.KeyArrowDown, ActionLeft: ebiten.KeyArrowLeft,
+			} {
+				if got, ok := s.keymap.action(k); !ok || got != a {
+					t.Errorf("%v is bound to %v, want %v", k, got, a)
+				}
+			}
+			if got, ok := keySchemeByName(s.name); !ok || got.label != s.label {
+				t.Errorf("keySchemeByName(%q) = %v", s.name, ok)
+			}
+		})
+	}
+}
+
+func TestButtonKeyYieldsToKeymap(t *testing.T) {
+	wasd, _ := keySchemeByName("wasd")
+	vim, _ := keySchemeByName("vim")
+	noop := func() error { return nil }
+	stats := newButton("统计", ebiten.KeyS, noop)
+	leaderboard := newButton("排行榜", ebiten.KeyL, noop)
+	quit := newButton("退出", ebiten.KeyQ, noop)
+	back := newBackButton("返回", noop)
+	tests := []struct {
+		name   string
+		button *button
+		keymap Keymap
+		want   bool
+	}{
+		{"free key", stats, defaultKeymap(), true},
+		{"S is down in wasd", stats, wasd.keymap, false},
+		{"Q is undo in wasd", quit, wasd.keymap, false},
+		{"L is right in vim", leaderboard, vim.keymap, false},
+		{"L is free in wasd", leaderboard, wasd.keymap, true},
+		{"Esc is pause", back, defaultKeymap(), true},
+		{"Esc is free", back, func() Keymap { m := defaultKeymap(); m.Unbind(ActionPause); return m }(), true},
+		{"Esc is undo", back, func() Keymap { m := defaultKeymap(); m.Bind(ActionUndo, ebiten.KeyEscape); return m }(), false},
+		{"no key", newButton("新玩家", -1, noop), defaultKeymap(), false},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := tt.button.keyEnabled(tt.keymap); got != tt.want {
+				t.Errorf("keyEnabled = %v, want %v", got, tt.want)
+			}
+		})
+	}
+	//输入文字的画面不使用按键绑定
+	cancel := newButton("取消", ebiten.KeyEscape, noop)
+	cancel.fixed = true
+	m := defaultKeymap()
+	m.Bind(ActionUndo, ebiten.KeyEscape)
+	if !cancel.keyEnabled(m) {
+		t.Errorf("fixed key yielded to the keymap")
+	}
+}
@@ -0,0 +1,357 @@
+package core
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"gameTest/engine"
+	"github.com/hajimehoshi/ebiten/v2"
+	"github.com/hajimehoshi/ebiten/v2/inpututil"
+	"hash/fnv"
+	"log"
+	"os"
+	"sort"
+	"strings"
+	"time"
+	"unicode/utf8"
+)
+
+const (
+	leaderboardVersion    = 1                  //排行榜文件的版本
+	leaderboardFileName   = "leaderboard.json" //排行榜的文件名
+	maxLeaderboardEntries = 10                 //每个排行榜保留几条记录
+	maxProfileName        = 12                 //玩家名字最多几个字
+	defaultProfile        = "玩家"               //没有创建玩家时使用的名字
+)
+
+// scoreEntry 排行榜上的一条记录
+type scoreEntry struct {
+	Name     string    `json:"name"`
+	Score    int       `json:"score"`
+	MaxTile  int       `json:"max_tile"`
+	Moves    int       `json:"moves"`
+	Duration int64     `json:"duration"` //用了多少毫秒
+	Seed     uint64    `json:"seed"`
+	Date     time.Time `json:"date"`
+	Begun    time.Time `json:"begun"` //开局的时间 区分不同的局
+}
+
+// leaderboard 本机所有玩家和每种棋盘的排行榜
+type leaderboard struct {
+	Version  int                      `json:"version"`
+	Profile  string                   `json:"profile"` //当前的玩家
+	Profiles []string                 `json:"profiles"`
+	Boards   map[string][]scoreEntry  `json:"boards"`          //键为boardKey
+	Stats    map[string]*profileStats `json:"stats,omitempty"` //每个玩家的统计
+}
+
+// newLeaderboard 只有默认玩家的空排行榜
+func newLeaderboard() *leaderboard {
+	return &leaderboard{
+		Version:  leaderboardVersion,
+		Profile:  defaultProfile,
+		Profiles: []string{defaultProfile},
+		Boards:   map[string][]scoreEntry{},
+	}
+}
+
+// boardKey 排行榜的键 棋盘大小和合并规则一样的棋盘在同一个排行榜
+// 有墙的关卡加上墙的位置的哈希，不同的关卡分开排名
+func boardKey(s engine.State) string {
+	w, h := s.Size()
+	key := fmt.Sprintf("%dx%d-%s", w, h, s.Rule().Name())
+	if len(s.Walls()) == 0 {
+		return key
+	}
+	f := fnv.New32a()
+	f.Write([]byte(engine.FormatLayout(s.Layout())))
+	return fmt.Sprintf("%s-%08x", key, f.Sum32())
+}
+
+// boardLabel 排行榜的标题 例如"4x4 经典"
+func boardLabel(key string) string {
+	parts := strings.SplitN(key, "-", 3)
+	if len(parts) < 2 {
+		return key
+	}
+	label := parts[0] + " " + ruleLabel(parts[1])
+	if len(parts) == 3 {
+		label += " 关卡"
+	}
+	return label
+}
+
+// ruleLabel 合并规则显示的名字
+func ruleLabel(name string) string {
+	if l, ok := ruleLabels[name]; ok {
+		return l
+	}
+	return name
+}
+
+// add 把记录加入key的排行榜，返回名次 没有进入排行榜时返回0
+// 同一局只保留最好的一次 固定种子的不同局分开记录
+func (l *leaderboard) add(key string, e scoreEntry) int {
+	entries := l.Boards[key]
+	for i, old := range entries {
+		if old.Name != e.Name || old.Begun.IsZero() || !old.Begun.Equal(e.Begun) {
+			continue
+		}
+		if e.Score <= old.Score {
+			return 0
+		}
+		entries = append(entries[:i], entries[i+1:]...)
+		break
+	}
+	entries = append(entries, e)
+	//分数相同时先达到的排在前面
+	sort.SliceStable(entries, func(i, j int) bool {
+		if entries[i].Score != entries[j].Score {
+			return entries[i].Score > entries[j].Score
+		}
+		return entries[i].Date.Before(entries[j].Date)
+	})
+	rank := 0
+	for i := range entries {
+		if entries[i] == e {
+			rank = i + 1
+			break
+		}
+	}
+	if maxLeaderboardEntries < len(entries) {
+		entries = entries[:maxLeaderboardEntries]
+	}
+	l.Boards[key] = entries
+	if maxLeaderboardEntries < rank {
+		return 0
+	}
+	return rank
+}
+
+// keys 有记录的排行榜的键 按名字排序
+func (l *leaderboard) keys() []string {
+	keys := make([]string, 0, len(l.Boards))
+	for k, entries := range l.Boards {
+		if 0 < len(entries) {
+			keys = append(keys, k)
+		}
+	}
+	sort.Strings(keys)
+	return keys
+}
+
+// addProfile 增加玩家并切换到这个玩家
+func (l *leaderboard) addProfile(name string) error {
+	name = strings.TrimSpace(name)
+	if name == "" {
+		return errors.New("twenty48: empty profile name")
+	}
+	if maxProfileName < utf8.RuneCountInString(name) {
+		return fmt.Errorf("twenty48: profile name longer than %d", maxProfileName)
+	}
+	for _, p := range l.Profiles {
+		if p == name {
+			l.Profile = name
+			return nil
+		}
+	}
+	l.Profiles = append(l.Profiles, name)
+	l.Profile = name
+	return nil
+}
+
+// nextProfile 切换到下一个玩家
+func (l *leaderboard) nextProfile() {
+	for i, p := range l.Profiles {
+		if p == l.Profile {
+			l.Profile = l.Profiles[(i+1)%len(l.Profiles)]
+			return
+		}
+	}
+	l.Profile = l.Profiles[0]
+}
+
+// loadLeaderboard 读取排行榜 没有文件时返回空的排行榜
+// 文件损坏或版本不对时把它改名留着，返回空的排行榜和错误
+func loadLeaderboard() (*leaderboard, error) {
+	path, err := configPath(leaderboardFileName)
+	if err != nil {
+		return newLeaderboard(), err
+	}
+	data, err := os.ReadFile(path)
+	if errors.Is(err, os.ErrNotExist) {
+		return newLeaderboard(), nil
+	}
+	if err != nil {
+		return newLeaderboard(), err
+	}
+	l, err := decodeLeaderboard(data)
+	if err != nil {
+		os.Rename(path, path+".bad")
+		return newLeaderboard(), err
+	}
+	return l, nil
+}
+
+// decodeLeaderboard 解码排行榜，检查版本
+func decodeLeaderboard(data []byte) (*leaderboard, error) {
+	l := &leaderboard{}
+	if err := json.Unmarshal(data, l); err != nil {
+		return nil, fmt.Errorf("twenty48: corrupt leaderboard: %w", err)
+	}
+	if l.Version != leaderboardVersion {
+		return nil, fmt.Errorf("twenty48: unsupported leaderboard version %d", l.Version)
+	}
+	if l.Boards == nil {
+		l.Boards = map[string][]scoreEntry{}
+	}
+	if len(l.Profiles) == 0 {
+		l.Profiles = []string{defaultProfile}
+	}
+	found := false
+	for _, p := range l.Profiles {
+		found = found || p == l.Profile
+	}
+	if !found {
+		l.Profile = l.Profiles[0]
+	}
+	return l, nil
+}
+
+// save 保存排行榜
+func (l *leaderboard) save() error {
+	path, err := configPath(leaderboardFileName)
+	if err != nil {
+		return err
+	}
+	data, err := json.MarshalIndent(l, "", "  ")
+	if err != nil {
+		return err
+	}
+	return writeFileAtomic(path, data)
+}
+
+// recordScore 这局结束时把分数记到当前玩家的排行榜和统计 返回名次
+// 回放、AI玩过的、一步没走的和已经记过的局不记录
+func (g *Game) recordScore() int {
+	b := g.board
+	if g.recorded || g.playback != nil || g.assisted || b.Moves() == 0 {
+		return 0
+	}
+	l := g.leaderboard
+	rank := l.add(boardKey(b.State()), scoreEntry{
+		Name:     l.Profile,
+		Score:    b.Score(),
+		MaxTile:  b.State().MaxTile(),
+		Moves:    b.Moves(),
+		Duration: b.Duration().Milliseconds(),
+		Seed:     b.Seed(),
+		Date:     time.Now(),
+		Begun:    b.begun,
+	})
+	//统计和排行榜保存在同一个文件
+	g.recordStats()
+	return rank
+}
+
+// leaderboardScene key的排行榜画面 可以切换排行榜和玩家
+func (g *Game) leaderboardScene(key string) *overlay {
+	l := g.leaderboard
+	lines := []string{"名次\t玩家\t分数\t最大\t步数\t用时\t日期"}
+	for i, e := range l.Boards[key] {
+		lines = append(lines, fmt.Sprintf("%d\t%s\t%d\t%d\t%d\t%s\t%s",
+			i+1, shorten(e.Name, 5), e.Score, e.MaxTile, e.Moves,
+			formatDuration(time.Duration(e.Duration)*time.Millisecond), e.Date.Format("01-02")))
+	}
+	if len(l.Boards[key]) == 0 {
+		lines = append(lines, "还没有记录")
+	}
+	//可以切换到有记录的排行榜和当前棋盘的排行榜
+	keys := l.keys()
+	current := boardKey(g.board.State())
+	if len(l.Boards[current]) == 0 {
+		keys = append(keys, current)
+		sort.Strings(keys)
+	}
+	i := sort.SearchStrings(keys, key)
+	switchTo := func(d int) func() error {
+		return func() error {
+			return g.refresh(g.leaderboardScene(keys[(i+d+len(keys))%len(keys)]))
+		}
+	}
+	//左右移动的按键切换排行榜
+	prev := newActionButton("上一个", ActionLeft, switchTo(-1))
+	next := newActionButton("下一个", ActionRight, switchTo(1))
+	prev.w, next.w = hudBoxWidth, hudBoxWidth
+	prev.disabled = len(keys) <= 1
+	next.disabled = len(keys) <= 1
+	profile := newButton("玩家:"+shorten(l.Profile, 4), ebiten.KeyP, func() error {
+		l.nextProfile()
+		if err := l.save(); err != nil {
+			log.Printf("保存排行榜失败: %v", err)
+		}
+		return g.refresh(g.leaderboardScene(key))
+	})
+	profile.disabled = len(l.Profiles) <= 1
+	newProfile := newButton("新玩家", -1, func() error {
+		return g.refresh(g.profileInputScene(key))
+	})
+	back := newBackButton("返回", g.back)
+	o := newTextOverlay(boardLabel(key), lines, 0, 0, g.ScreenWidth, g.ScreenHeight,
+		prev, next, profile, newProfile, back)
+	o.columns = []int{1, 2, 2, 1, 1, 1, 1}
+	return o
+}
+
+// profileInputScene 输入新玩家的名字 确定后回到key的排行榜
+func (g *Game) profileInputScene(key string) *overlay {
+	var name []rune
+	ok := newButton("确定", ebiten.KeyEnter, func() error {
+		if err := g.leaderboard.addProfile(string(name)); err != nil {
+			return nil
+		}
+		if err := g.leaderboard.save(); err != nil {
+			log.Printf("保存排行榜失败: %v", err)
+		}
+		return g.refresh(g.leaderboardScene(key))
+	})
+	ok.disabled = true
+	cancel := newButton("取消", ebiten.KeyEscape, func() error {
+		return g.refresh(g.leaderboardScene(key))
+	})
+	//输入名字时所有的按键都是文字
+	ok.fixed, cancel.fixed = true, true
+	o := newTextOverlay("新玩家", []string{"_"}, 0, 0, g.ScreenWidth, g.ScreenHeight, ok, cancel)
+	o.onUpdate = func() error {
+		for _, r := range ebiten.AppendInputChars(nil) {
+			if len(name) < maxProfileName {
+				name = append(name, r)
+			}
+		}
+		if inpututil.IsKeyJustPressed(ebiten.KeyBackspace) && 0 < len(name) {
+			name = name[:len(name)-1]
+		}
+		o.lines[0] = string(name) + "_"
+		ok.disabled = strings.TrimSpace(string(name)) == ""
+		return nil
+	}
+	return o
+}
+
+// shorten 超过n个字时截断
+func shorten(s string, n int) string {
+	r := []rune(s)
+	if len(r) <= n {
+		return s
+	}
+	return string(r[:n-1]) + "…"
+}
+
+// formatDuration 用时 例如"3:05"，超过一小时为"1:03:05"
+func formatDuration(d time.Duration) string {
+	s := int(d / time.Second)
+	if 3600 <= s {
+		return fmt.Sprintf("%d:%02d:%02d", s/3600, s/60%60, s%60)
+	}
+	return fmt.Sprintf("%d:%02d", s/60, s%60)
+}
@@ -0,0 +1,56 @@
+package core
+
+import (
+	"testing"
+)
+
+// playUntil 一直走到棋盘上有v
+func playUntil(t *testing.T, g *Game, v int) {
+	t.Helper()
+	for !g.board.State().Reached(v) {
+		step(t, g.board)
+	}
+}
+
+func TestRecordWonGame(t *testing.T) {
+	g := testGame(t, Options{Seed: 1, WinTarget: 16})
+	playUntil(t, g, 16)
+	key := boardKey(g.board.State())
+	score := g.board.Score()
+	//赢了之后直接开始新的一局
+	if err := g.newGame(); err != nil {
+		t.Fatal(err)
+	}
+	entries := g.leaderboard.Boards[key]
+	if len(entries) != 1 || entries[0].Score != score {
+		t.Fatalf("leaderboard = %+v, want one entry with score %d", entries, score)
+	}
+	//没有赢的局放弃时不进排行榜
+	step(t, g.board)
+	if err := g.newGame(); err != nil {
+		t.Fatal(err)
+	}
+	if n := len(g.leaderboard.Boards[key]); n != 1 {
+		t.Errorf("leaderboard has %d entries after abandoning, want 1", n)
+	}
+}
+
+func TestRecordOnce(t *testing.T) {
+	g := testGame(t, Options{Seed: 2, WinTarget: 16})
+	playUntil(t, g, 16)
+	key := boardKey(g.board.State())
+	g.recordScore()
+	g.recordScore()
+	if err := g.newGame(); err != nil {
+		t.Fatal(err)
+	}
+	if n := len(g.leaderboard.Boards[key]); n != 1 {
+		t.Errorf("leaderboard has %d entries, want 1", n)
+	}
+	//AI走过的局不记录
+	g.assisted = true
+	playUntil(t, g, 16)
+	if g.recordScore() != 0 || len(g.leaderboard.Boards[key]) != 1 {
+		t.Errorf("assisted game was recorded")
+	}
+}
@@ -0,0 +1,100 @@
+package core
+
+import (
+	"gameTest/engine"
+	"github.com/hajimehoshi/ebiten/v2"
+	"strconv"
+)
+
+const (
+	sizeButtonWidth = 80 //选择棋盘大小的按钮宽度
+)
+
+// boardPresets 菜单中可以选择的棋盘大小 列数x行数
+var boardPresets = [][2]int{
+	{3, 3}, {4, 4}, {5, 5}, {6, 6}, {7, 7}, {8, 8},
+	{5, 3}, {6, 4}, {3, 5}, {4, 6},
+}
+
+// ruleLabels 合并规则在菜单上显示的名字
+var ruleLabels = map[string]string{
+	"classic":   "经典",
+	"fibonacci": "斐波那契",
+	"pow3":      "三的幂",
+	"threes":    "Threes",
+}
+
+// openPause 暂停游戏，打开暂停画面
+func (g *Game) openPause() error {
+	return g.open(g.pauseScene())
+}
+
+// pauseScene 暂停画面 盖在棋盘上，可以继续、开始新的一局或者打开其他画面
+func (g *Game) pauseScene() *overlay {
+	lines := []string{
+		"分数\t" + strconv.Itoa(g.board.Score()),
+		"步数\t" + strconv.Itoa(g.board.Moves()),
+	}
+	//再按一次暂停回到游戏
+	resume := newActionButton("继续", ActionPause, g.back)
+	restart := newActionButton("重新开始", ActionRestart, g.newGame)
+	menu := newButton("新游戏", ebiten.KeyM, func() error {
+		return g.open(g.menuScene())
+	})
+	leaderboard := newButton("排行榜", ebiten.KeyL, func() error {
+		return g.open(g.leaderboardScene(boardKey(g.board.State())))
+	})
+	stats := newButton("统计", ebiten.KeyS, func() error {
+		return g.open(g.statsScene(""))
+	})
+	settings := newButton("设置", ebiten.KeyO, func() error {
+		return g.open(g.settingsScene(""))
+	})
+	//标题画面替换暂停画面，从标题继续时直接回到棋盘
+	title := newButton("标题", ebiten.KeyT, func() error {
+		g.scenes.replace(g.titleScene(true), transitionFade)
+		return nil
+	})
+	return newTextOverlay("暂停", lines, 0, 0, g.ScreenWidth, g.ScreenHeight,
+		resume, restart, menu, leaderboard, stats, settings, title)
+}
+
+// menuScene 选择棋盘大小或合并规则开始新的一局
+func (g *Game) menuScene() *overlay {
+	cols, rows := g.board.Dims()
+	var buttons []*button
+	for _, p := range boardPresets {
+		p := p
+		label := strconv.Itoa(p[0]) + "x" + strconv.Itoa(p[1])
+		//正方形的棋盘可以用数字键选择
+		key := ebiten.Key(-1)
+		if p[0] == p[1] {
+			key = ebiten.KeyDigit0 + ebiten.Key(p[0])
+		}
+		b := newButton(label, key, func() error {
+			g.options.Width = p[0]
+			g.options.Height = p[1]
+			g.options.Walls = nil
+			return g.newGame()
+		})
+		b.w = sizeButtonWidth
+		//当前的棋盘大小不能再选
+		b.disabled = p[0] == cols && p[1] == rows
+		buttons = append(buttons, b)
+	}
+	for _, r := range engine.Rules {
+		r := r
+		b := newButton(ruleLabels[r.Name()], -1, func() error {
+			g.options.Rule = r
+			return g.newGame()
+		})
+		b.w = hudBoxWidth
+		b.disabled = r == g.board.State().Rule()
+		buttons = append(buttons, b)
+	}
+	//当前的大小和规则再来一局
+	buttons = append(buttons, newActionButton("重新开始", ActionRestart, g.newGame))
+	buttons = append(buttons, newBackButton("返回", g.back))
+	//菜单盖住整个画面
+	return newOverlay("新游戏", 0, 0, g.ScreenWidth, g.ScreenHeight, buttons...)
+}
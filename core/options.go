@@ -0,0 +1,69 @@
+package core
+
+import (
+	"gameTest/ai"
+	"gameTest/engine"
+	"time"
+)
+
+// Options 开始游戏时的设置
+type Options struct {
+	Width       int              //棋盘的列数 0为DefaultBoardSize
+	Height      int              //棋盘的行数 0为DefaultBoardSize
+	Walls       []engine.Pos     //墙的位置
+	WinTarget   int              //合并出这个值就算赢 0为合并规则的目标
+	Rule        engine.MergeRule //合并规则 nil为engine.Classic
+	UndoLimit   int              //每局最多撤销几次 0为不限制
+	Seed        uint64           //每局的随机种子 0为每局使用新的种子
+	Replay      *engine.Replay   //开始时回放的录像 nil为正常游戏
+	Autoplay    bool             //开始时是否由AI自动玩
+	AI          ai.Options       //AI搜索的设置
+	Theme       string           //主题的名字或者主题文件的路径 空为设置中保存的主题
+	MoveQueue   int              //动画时最多缓存几步移动 0或者负数为不缓存
+	FastForward bool             //动画时收到新的移动立即走完动画，不缓存
+	SkipTitle   bool             //不显示标题画面，直接开始游戏
+	NoResume    bool             //不继续存档中的一局 命令行指定了棋盘大小或规则时设置
+}
+
+// layout 新的一局使用的棋盘布局 不在棋盘内的墙会被忽略
+func (o Options) layout() engine.State {
+	s := engine.New(o.Width, o.Height).WithRule(o.Rule)
+	for _, p := range o.Walls {
+		if 0 <= p.X && p.X < o.Width && 0 <= p.Y && p.Y < o.Height {
+			s = s.Set(p.X, p.Y, engine.Wall)
+		}
+	}
+	return s
+}
+
+// newSource 新的一局使用的随机数
+func (o Options) newSource() *engine.Source {
+	seed := o.Seed
+	if seed == 0 {
+		seed = uint64(time.Now().UnixNano())
+	}
+	return engine.NewSource(seed)
+}
+
+// withDefaults 没有设置的项使用默认值
+func (o Options) withDefaults() Options {
+	if o.AI.Budget <= 0 {
+		o.AI.Budget = ai.DefaultBudget
+	}
+	o.Width = clampBoardSize(o.Width)
+	o.Height = clampBoardSize(o.Height)
+	return o
+}
+
+// clampBoardSize 把行数或列数限制在MinBoardSize和MaxBoardSize之间 0为DefaultBoardSize
+func clampBoardSize(n int) int {
+	switch {
+	case n <= 0:
+		return DefaultBoardSize
+	case n < MinBoardSize:
+		return MinBoardSize
+	case MaxBoardSize < n:
+		return MaxBoardSize
+	}
+	return n
+}
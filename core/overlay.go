@@ -0,0 +1,161 @@
+package core
+
+import (
+	"github.com/hajimehoshi/ebiten/v2"
+	"strings"
+)
+
+const (
+	overlayTitleHeight = 80 //按钮很多或者有文字时标题区域的高度
+	overlayLineHeight  = 24 //每行文字的高度
+)
+
+// overlay 盖在棋盘上的画面，显示标题、几行文字和一排按钮
+type overlay struct {
+	x        int //覆盖区域的位置
+	y        int //覆盖区域的位置
+	w        int //覆盖区域的宽高
+	h        int //覆盖区域的宽高
+	title    string
+	titleH   int      //标题区域的高度
+	lines    []string //标题下面的文字 用\t分成几列
+	columns  []int    //每一列的宽度比例 nil为平分
+	buttons  []*button
+	onUpdate func() error //每一帧更新按钮之前调用 可以为nil
+	opaque   bool         //用背景色盖住下面的画面 例如标题画面
+
+	bodyH int                                     //文字下面自己绘制的区域的高度
+	body  func(dst *ebiten.Image, x, y, w, h int) //绘制文字下面的区域 可以为nil
+}
+
+// newOverlay 初始化覆盖在(x,y,w,h)区域上的画面
+func newOverlay(title string, x, y, w, h int, buttons ...*button) *overlay {
+	return newTextOverlay(title, nil, x, y, w, h, buttons...)
+}
+
+// newTextOverlay 初始化标题下面有几行文字的画面 文字的行数之后不能再变
+func newTextOverlay(title string, lines []string, x, y, w, h int, buttons ...*button) *overlay {
+	o := &overlay{
+		x:       x,
+		y:       y,
+		w:       w,
+		h:       h,
+		title:   title,
+		lines:   lines,
+		buttons: buttons,
+	}
+	o.layout()
+	return o
+}
+
+// setBody 在文字和按钮之间留出h高的区域，由draw绘制
+func (o *overlay) setBody(h int, draw func(dst *ebiten.Image, x, y, w, h int)) {
+	o.bodyH = h
+	o.body = draw
+	o.layout()
+}
+
+// layout 计算标题的高度和按钮的位置
+func (o *overlay) layout() {
+	x, y, w, h := o.x, o.y, o.w, o.h
+	buttons := o.buttons
+	//按钮横向居中排列，一行放不下时换行
+	var rows [][]*button
+	for len(buttons) > 0 {
+		n, width := 0, 0
+		for n < len(buttons) {
+			bw := buttons[n].w
+			if 0 < n {
+				bw += hudMargin
+			}
+			if 0 < n && w < width+bw {
+				break
+			}
+			width += bw
+			n++
+		}
+		rows = append(rows, buttons[:n])
+		buttons = buttons[n:]
+	}
+	//按钮放在下半部分，放不下或者有文字时标题只占上面一行
+	o.titleH = h / 2
+	if height := len(rows) * (buttonHeight + hudMargin); h-o.titleH < height+hudMargin || 0 < len(o.lines) || o.body != nil {
+		o.titleH = overlayTitleHeight
+	}
+	by := y + o.titleH + len(o.lines)*overlayLineHeight + o.bodyH + hudMargin
+	for _, row := range rows {
+		width := -hudMargin
+		for _, b := range row {
+			width += b.w + hudMargin
+		}
+		bx := x + (w-width)/2
+		for _, b := range row {
+			b.setXY(bx, by)
+			bx += b.w + hudMargin
+		}
+		by += buttonHeight + hudMargin
+	}
+}
+
+// Update 更新按钮
+func (o *overlay) Update(input *Input) error {
+	if o.onUpdate != nil {
+		if err := o.onUpdate(); err != nil {
+			return err
+		}
+	}
+	for _, b := range o.buttons {
+		if err := b.Update(input); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// Draw 绘制半透明的背景、标题和按钮
+func (o *overlay) Draw(screen *ebiten.Image) {
+	op := &ebiten.DrawImageOptions{}
+	op.GeoM.Scale(float64(o.w), float64(o.h))
+	op.GeoM.Translate(float64(o.x), float64(o.y))
+	if o.opaque {
+		op.ColorScale.ScaleWithColor(theme.Background)
+	} else {
+		op.ColorScale.ScaleWithColor(theme.Overlay)
+	}
+	screen.DrawImage(buttonImage, op)
+	//标题在按钮上方
+	drawTextCenter(screen, o.title, mplusBigFont, o.x, o.y, o.w, o.titleH, theme.Title)
+	o.drawLines(screen)
+	if o.body != nil {
+		o.body(screen, o.x, o.y+o.titleH+len(o.lines)*overlayLineHeight, o.w, o.bodyH)
+	}
+	for _, b := range o.buttons {
+		b.Draw(screen)
+	}
+}
+
+// drawLines 在标题下面绘制文字 每一列在自己的宽度内居中
+func (o *overlay) drawLines(screen *ebiten.Image) {
+	for i, line := range o.lines {
+		cells := strings.Split(line, "\t")
+		weights := o.columns
+		if len(weights) != len(cells) {
+			weights = make([]int, len(cells))
+			for j := range weights {
+				weights[j] = 1
+			}
+		}
+		total := 0
+		for _, w := range weights {
+			total += w
+		}
+		y := o.y + o.titleH + i*overlayLineHeight
+		x := o.x + hudMargin
+		width := o.w - 2*hudMargin
+		for j, c := range cells {
+			cw := width * weights[j] / total
+			drawTextCenter(screen, c, mplusTinyFont, x, y, cw, overlayLineHeight, theme.Title)
+			x += cw
+		}
+	}
+}
@@ -0,0 +1,53 @@
+package core
+
+// DefaultMoveQueue 动画时默认最多缓存几步移动
+const DefaultMoveQueue = 4
+
+// moveQueue 动画进行时收到的移动 棋盘停下来后按顺序执行
+type moveQueue struct {
+	dirs        []Dir
+	limit       int  //最多缓存几步 0为不缓存
+	fastForward bool //收到新的移动时立即走完当前的动画
+}
+
+// SetMoveQueue 设置动画时最多缓存几步移动 0为不缓存
+// fastForward为true时新的移动会立即走完当前的动画，不用等
+func (b *Board) SetMoveQueue(limit int, fastForward bool) {
+	if limit < 0 {
+		limit = 0
+	}
+	b.queue.limit = limit
+	b.queue.fastForward = fastForward
+	if limit < len(b.queue.dirs) {
+		b.queue.dirs = b.queue.dirs[:limit]
+	}
+}
+
+// QueuedMoves 还没有执行的移动
+func (b *Board) QueuedMoves() []Dir {
+	return b.queue.dirs
+}
+
+// ClearQueue 丢弃还没有执行的移动 撤销和回放时使用
+func (b *Board) ClearQueue() {
+	b.queue.dirs = b.queue.dirs[:0]
+}
+
+// push 缓存一步移动 缓存满了时丢弃这一步，返回false
+func (q *moveQueue) push(dir Dir) bool {
+	if q.limit <= len(q.dirs) {
+		return false
+	}
+	q.dirs = append(q.dirs, dir)
+	return true
+}
+
+// pop 取出最早的一步
+func (q *moveQueue) pop() (Dir, bool) {
+	if len(q.dirs) == 0 {
+		return 0, false
+	}
+	dir := q.dirs[0]
+	q.dirs = append(q.dirs[:0], q.dirs[1:]...)
+	return dir, true
+}
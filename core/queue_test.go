@@ -0,0 +1,86 @@
+package core
+
+import (
+	"reflect"
+	"testing"
+)
+
+func TestMoveQueue(t *testing.T) {
+	q := moveQueue{limit: 2}
+	if _, ok := q.pop(); ok {
+		t.Fatal("pop from an empty queue")
+	}
+	if !q.push(DirUp) || !q.push(DirLeft) {
+		t.Fatal("push within the limit failed")
+	}
+	//满了之后丢弃新的移动
+	if q.push(DirDown) {
+		t.Error("push past the limit succeeded")
+	}
+	for _, want := range []Dir{DirUp, DirLeft} {
+		if d, ok := q.pop(); !ok || d != want {
+			t.Errorf("pop = %v, %v, want %v", d, ok, want)
+		}
+	}
+	if _, ok := q.pop(); ok {
+		t.Error("pop after draining the queue")
+	}
+}
+
+func TestMoveQueueZero(t *testing.T) {
+	q := moveQueue{}
+	if q.push(DirUp) {
+		t.Error("push with limit 0 succeeded")
+	}
+	b := testBoard(t, 1)
+	b.SetMoveQueue(-3, false)
+	if b.queue.limit != 0 {
+		t.Errorf("negative limit = %d, want 0", b.queue.limit)
+	}
+	if b.queue.push(DirUp) || len(b.QueuedMoves()) != 0 {
+		t.Errorf("queued %v with limit 0", b.QueuedMoves())
+	}
+}
+
+func TestSetMoveQueue(t *testing.T) {
+	b := testBoard(t, 1)
+	b.SetMoveQueue(3, true)
+	if !b.queue.fastForward {
+		t.Error("fast forward not set")
+	}
+	for _, d := range []Dir{DirUp, DirRight, DirDown} {
+		b.queue.push(d)
+	}
+	//缩小上限时丢掉多出来的移动
+	b.SetMoveQueue(1, false)
+	if got := b.QueuedMoves(); !reflect.DeepEqual(got, []Dir{DirUp}) {
+		t.Errorf("queue after shrinking = %v, want [Up]", got)
+	}
+	b.ClearQueue()
+	if len(b.QueuedMoves()) != 0 {
+		t.Errorf("queue after ClearQueue = %v", b.QueuedMoves())
+	}
+}
+
+func TestQueuedMovesPlayInOrder(t *testing.T) {
+	b := testBoard(t, 1)
+	b.SetMoveQueue(4, false)
+	//种子1开局两个格子都在第一行，向下一定能移动
+	if err := b.Move(DirDown); err != nil {
+		t.Fatal(err)
+	}
+	b.queue.push(DirUp)
+	b.queue.push(DirRight)
+	for i := 0; i < 1000 && !b.IsSettled(); i++ {
+		if err := b.Update(nil); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if !b.IsSettled() {
+		t.Fatal("the board never settled")
+	}
+	dirs := b.Replay().Dirs()
+	if len(dirs) < 2 || dirs[0] != DirDown || dirs[1] != DirUp {
+		t.Errorf("played %v, want Down then Up first", dirs)
+	}
+}
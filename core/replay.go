@@ -0,0 +1,162 @@
+package core
+
+import (
+	"fmt"
+	"gameTest/engine"
+	"github.com/hajimehoshi/ebiten/v2"
+	"os"
+	"path/filepath"
+	"strconv"
+	"time"
+)
+
+const (
+	replayDirName   = "replays"       //录像保存的目录
+	maxPlaybackWait = 2 * time.Second //回放时两步之间最多等多久
+)
+
+// playbackSpeeds 回放可以选择的速度
+var playbackSpeeds = []float64{0.5, 1, 2, 4, 8}
+
+// writeReplay 把这局的录像写到配置目录下的replays目录
+func (b *Board) writeReplay() (string, error) {
+	if b.record == nil || len(b.record.Moves) == 0 {
+		return "", nil
+	}
+	dir, err := configPath(replayDirName)
+	if err != nil {
+		return "", err
+	}
+	if err := os.MkdirAll(dir, 0o755); err != nil {
+		return "", err
+	}
+	name := fmt.Sprintf("%s-%d.json", b.started.Format("20060102-150405"), b.Seed())
+	path := filepath.Join(dir, name)
+	f, err := os.Create(path)
+	if err != nil {
+		return "", err
+	}
+	if _, err := b.record.WriteTo(f); err != nil {
+		f.Close()
+		return "", err
+	}
+	return path, f.Close()
+}
+
+// playback 回放录像的状态
+type playback struct {
+	replay *engine.Replay
+	clock  time.Duration //回放进行到的时间
+	speed  int           //playbackSpeeds的下标
+	paused bool
+}
+
+// startPlayback 从头开始回放录像
+func (g *Game) startPlayback(r *engine.Replay) error {
+	board, err := NewBoard(g.ScreenWidth, g.ScreenHeight, r.Layout(), engine.NewSource(r.Seed))
+	if err != nil {
+		return err
+	}
+	g.board = board
+	g.scenes.popAll(transitionNone)
+	g.gainCount = 0
+	g.playback = &playback{
+		replay: r,
+		speed:  1,
+	}
+	return nil
+}
+
+// seek 回放跳到第n步
+func (p *playback) seek(b *Board, n int) error {
+	state, src, err := p.replay.Seek(n)
+	if err != nil {
+		return err
+	}
+	b.finishTasks()
+	b.restore(snapshot{state: state, rng: src.State(), moves: n})
+	p.clock = 0
+	if 0 < n {
+		p.clock = time.Duration(p.replay.Moves[n-1].Time) * time.Millisecond
+	}
+	return nil
+}
+
+// step 回放下一步
+func (p *playback) step(b *Board) error {
+	n := b.Moves()
+	if len(p.replay.Moves) <= n {
+		return nil
+	}
+	m := p.replay.Moves[n]
+	p.clock = time.Duration(m.Time) * time.Millisecond
+	return b.Move(m.Dir)
+}
+
+// updatePlayback 回放时的更新 input为nil时不接受按键
+// 按键和手柄使用玩家绑定的操作：自动玩暂停和继续，重做和→下一步，撤销和←上一步，
+// ↑↓调整速度，暂停退出回放 方向也可以滑动
+func (g *Game) updatePlayback(input *Input) error {
+	p := g.playback
+	b := g.board
+	if input != nil {
+		dir, moved := input.Dir()
+		switch {
+		case input.Pressed(ActionPause):
+			g.playback = nil
+			return g.newGame()
+		case input.Pressed(ActionAutoplay):
+			p.paused = !p.paused
+		case input.Pressed(ActionRedo) || moved && dir == DirRight:
+			p.paused = true
+			if err := p.step(b); err != nil {
+				return err
+			}
+		case input.Pressed(ActionUndo) || moved && dir == DirLeft:
+			p.paused = true
+			if 0 < b.Moves() {
+				if err := p.seek(b, b.Moves()-1); err != nil {
+					return err
+				}
+			}
+		case moved && dir == DirUp:
+			if p.speed < len(playbackSpeeds)-1 {
+				p.speed++
+			}
+		case moved && dir == DirDown:
+			if 0 < p.speed {
+				p.speed--
+			}
+		}
+	}
+	if err := b.Update(nil); err != nil {
+		return err
+	}
+	if p.paused || !b.IsSettled() {
+		return nil
+	}
+	n := b.Moves()
+	if len(p.replay.Moves) <= n {
+		return nil
+	}
+	//按录像的时间回放，等太久的直接跳过
+	p.clock += time.Duration(playbackSpeeds[p.speed] * float64(time.Second) / float64(ebiten.TPS()))
+	next := time.Duration(p.replay.Moves[n].Time) * time.Millisecond
+	if p.clock+maxPlaybackWait < next {
+		p.clock = next - maxPlaybackWait
+	}
+	if p.clock < next {
+		return nil
+	}
+	return p.step(b)
+}
+
+// status 回放的进度 显示在HUD上
+func (p *playback) status(b *Board) string {
+	s := "回放 " + strconv.Itoa(b.Moves()) + "/" + strconv.Itoa(len(p.replay.Moves)) +
+		" x" + strconv.FormatFloat(playbackSpeeds[p.speed], 'g', -1, 64)
+	if p.paused {
+		s += " 暂停"
+	}
+	return s
+}
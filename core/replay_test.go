@@ -0,0 +1,50 @@
+package core
+
+import (
+	"bytes"
+	"gameTest/engine"
+	"testing"
+)
+
+func TestPlayback(t *testing.T) {
+	g := testGame(t, Options{Seed: 6})
+	playMoves(t, g, 5)
+	var buf bytes.Buffer
+	if _, err := g.board.Replay().WriteTo(&buf); err != nil {
+		t.Fatal(err)
+	}
+	r, err := engine.ReadReplay(&buf)
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := g.board.State()
+
+	if err := g.startPlayback(r); err != nil {
+		t.Fatal(err)
+	}
+	p := g.playback
+	for i := 0; i < len(r.Moves); i++ {
+		if err := p.step(g.board); err != nil {
+			t.Fatal(err)
+		}
+		g.board.finishTasks()
+	}
+	if !sameState(g.board.State(), want) {
+		t.Errorf("played back %v, want %v", g.board.State(), want)
+	}
+	//走完之后不再移动
+	if err := p.step(g.board); err != nil || g.board.Moves() != len(r.Moves) {
+		t.Errorf("step past the end = %v, moves %d", err, g.board.Moves())
+	}
+
+	if err := p.seek(g.board, 2); err != nil {
+		t.Fatal(err)
+	}
+	state, _, _ := r.Seek(2)
+	if !sameState(g.board.State(), state) || g.board.Moves() != 2 || len(g.board.Replay().Moves) != 2 {
+		t.Errorf("after seek: %d moves, replay has %d", g.board.Moves(), len(g.board.Replay().Moves))
+	}
+	if err := p.seek(g.board, len(r.Moves)+1); err == nil {
+		t.Errorf("seek past the end succeeded")
+	}
+}
@@ -0,0 +1,228 @@
+package core
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"gameTest/engine"
+	"os"
+	"time"
+)
+
+const (
+	saveVersion  = 2           //存档的版本
+	saveFileName = "save.json" //存档的文件名
+)
+
+// saveFile 存档的内容
+type saveFile struct {
+	Version     int             `json:"version"`
+	Seed        uint64          `json:"seed"`
+	Board       savedSnapshot   `json:"board"`
+	Undo        []savedSnapshot `json:"undo"`
+	Redo        []savedSnapshot `json:"redo"`
+	UndosUsed   int             `json:"undos_used"`
+	Best        int             `json:"best"`
+	KeepPlaying bool            `json:"keep_playing"`
+	Assisted    bool            `json:"assisted,omitempty"`
+	Recorded    bool            `json:"recorded,omitempty"`
+	Begun       time.Time       `json:"begun"`
+	Replay      *engine.Replay  `json:"replay,omitempty"`
+}
+
+// savedSnapshot 存档中的一步
+type savedSnapshot struct {
+	State engine.State `json:"state"`
+	Rand  uint64       `json:"rand"`
+	Moves int          `json:"moves"`
+
+	Undone *engine.ReplayMove `json:"undone,omitempty"`
+}
+
+func toSaved(s snapshot) savedSnapshot {
+	return savedSnapshot{State: s.state, Rand: s.rng, Moves: s.moves, Undone: s.undone}
+}
+
+func fromSaved(s savedSnapshot) snapshot {
+	return snapshot{state: s.State, rng: s.Rand, moves: s.Moves, undone: s.Undone}
+}
+
+// encodeSave 把游戏编码成存档
+func (g *Game) encodeSave() ([]byte, error) {
+	b := g.board
+	f := saveFile{
+		Version:     saveVersion,
+		Seed:        b.Seed(),
+		Board:       toSaved(b.snapshot()),
+		UndosUsed:   b.history.used,
+		Best:        g.best,
+		KeepPlaying: g.keepPlaying,
+		Assisted:    g.assisted,
+		Recorded:    g.recorded,
+		Begun:       b.begun,
+		Replay:      b.record,
+	}
+	for _, s := range b.history.undo {
+		f.Undo = append(f.Undo, toSaved(s))
+	}
+	for _, s := range b.history.redo {
+		f.Redo = append(f.Redo, toSaved(s))
+	}
+	return json.MarshalIndent(f, "", "  ")
+}
+
+// newerSaveError 存档是更新的版本写的 不能读取，但是不算损坏
+type newerSaveError struct {
+	version int
+}
+
+func (e *newerSaveError) Error() string {
+	return fmt.Sprintf("twenty48: save version %d is newer than %d", e.version, saveVersion)
+}
+
+// decodeSave 解码存档，把旧版本升级到现在的版本，检查棋盘
+func decodeSave(data []byte) (*saveFile, error) {
+	f := &saveFile{}
+	if err := json.Unmarshal(data, f); err != nil {
+		return nil, fmt.Errorf("twenty48: corrupt save file: %w", err)
+	}
+	if err := upgradeSave(f); err != nil {
+		return nil, err
+	}
+	//所有记录的棋盘大小和墙必须一样
+	cols, rows := f.Board.State.Size()
+	if cols != clampBoardSize(cols) || rows != clampBoardSize(rows) {
+		return nil, fmt.Errorf("twenty48: unsupported board size %dx%d", cols, rows)
+	}
+	layout := engine.FormatLayout(f.Board.State)
+	for _, s := range append(append([]savedSnapshot{}, f.Undo...), f.Redo...) {
+		if c, r := s.State.Size(); c != cols || r != rows || engine.FormatLayout(s.State) != layout {
+			return nil, errors.New("twenty48: corrupt save file: board layout mismatch")
+		}
+	}
+	return f, nil
+}
+
+// loadBest 只读取存档中的最高分 不继续上次的游戏时也保留最高分，自动保存时不会被清零
+func (g *Game) loadBest() error {
+	path, err := configPath(saveFileName)
+	if err != nil {
+		return err
+	}
+	data, err := os.ReadFile(path)
+	if errors.Is(err, os.ErrNotExist) {
+		return nil
+	}
+	if err != nil {
+		return err
+	}
+	var f struct {
+		Best int `json:"best"`
+	}
+	if err := json.Unmarshal(data, &f); err != nil {
+		return fmt.Errorf("twenty48: corrupt save file: %w", err)
+	}
+	g.best = f.Best
+	return nil
+}
+
+// upgradeSave 按版本一步一步升级到saveVersion
+// 存档格式变化时增加saveVersion，并在这里加上从上一个版本升级的一步
+func upgradeSave(f *saveFile) error {
+	for f.Version < saveVersion {
+		switch f.Version {
+		case 1:
+			//版本1的存档可能没有开局时间，按读档的时间算作新的一局
+			if f.Begun.IsZero() {
+				f.Begun = time.Now()
+			}
+		default:
+			return fmt.Errorf("twenty48: unsupported save version %d", f.Version)
+		}
+		f.Version++
+	}
+	if saveVersion < f.Version {
+		return &newerSaveError{version: f.Version}
+	}
+	return nil
+}
+
+// save 保存游戏
+func (g *Game) save() error {
+	path, err := configPath(saveFileName)
+	if err != nil {
+		return err
+	}
+	data, err := g.encodeSave()
+	if err != nil {
+		return err
+	}
+	return writeFileAtomic(path, data)
+}
+
+// load 读取存档恢复游戏
+// 没有存档时返回false，存档损坏或者是更新的版本时把它改名留着，返回错误
+func (g *Game) load() (bool, error) {
+	path, err := configPath(saveFileName)
+	if err != nil {
+		return false, err
+	}
+	data, err := os.ReadFile(path)
+	if errors.Is(err, os.ErrNotExist) {
+		return false, nil
+	}
+	if err != nil {
+		return false, err
+	}
+	f, err := decodeSave(data)
+	var newer *newerSaveError
+	if errors.As(err, &newer) {
+		//留着给新版本读取，不被这次的自动保存覆盖
+		os.Rename(path, fmt.Sprintf("%s.v%d", path, newer.version))
+		return false, err
+	}
+	if err != nil {
+		//留着坏掉的存档方便查找原因，下次不再读取
+		os.Rename(path, path+".bad")
+		return false, err
+	}
+	layout := f.Board.State.Layout()
+	cols, rows := layout.Size()
+	b := newBoard(g.ScreenWidth, g.ScreenHeight, layout, engine.NewSource(f.Seed))
+	b.SetUndoLimit(g.options.UndoLimit)
+	b.SetMoveQueue(g.options.MoveQueue, g.options.FastForward)
+	b.restore(fromSaved(f.Board))
+	for _, s := range f.Undo {
+		b.history.undo = append(b.history.undo, fromSaved(s))
+	}
+	for _, s := range f.Redo {
+		b.history.redo = append(b.history.redo, fromSaved(s))
+	}
+	b.history.used = f.UndosUsed
+	//录像和步数对不上时不再录像
+	b.record = f.Replay
+	if b.record != nil && len(b.record.Moves) != b.moves {
+		b.record = nil
+	}
+	if b.moves == 0 && b.record == nil {
+		b.record = engine.NewReplay(layout, f.Seed)
+	}
+	//接着上次最后一步的时间继续计时
+	if b.record != nil && 0 < len(b.record.Moves) {
+		last := b.record.Moves[len(b.record.Moves)-1]
+		b.started = time.Now().Add(-time.Duration(last.Time) * time.Millisecond)
+	}
+	b.begun = f.Begun
+	g.board = b
+	//之后的新游戏使用存档的棋盘大小
+	g.options.Width = cols
+	g.options.Height = rows
+	g.options.Walls = layout.Walls()
+	g.options.Rule = layout.Rule()
+	g.best = f.Best
+	g.keepPlaying = f.KeepPlaying
+	g.assisted = f.Assisted
+	g.recorded = f.Recorded
+	g.scenes.popAll(transitionNone)
+	return true, nil
+}
@@ -0,0 +1,166 @@
+package core
+
+import (
+	"encoding/json"
+	"os"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+// testGame 在临时的配置目录中开始新的一局
+func testGame(t *testing.T, options Options) *Game {
+	t.Helper()
+	t.Setenv("XDG_CONFIG_HOME", t.TempDir())
+	options.SkipTitle = true
+	g, err := NewGame(420, 600, options)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return g
+}
+
+// playMoves 在g的棋盘上走n步
+func playMoves(t *testing.T, g *Game, n int) {
+	t.Helper()
+	for i := 0; i < n; i++ {
+		step(t, g.board)
+	}
+}
+
+func TestSaveRoundTrip(t *testing.T) {
+	g := testGame(t, Options{Seed: 5, UndoLimit: 10})
+	playMoves(t, g, 6)
+	g.board.Undo()
+	g.best = 1234
+	g.keepPlaying = true
+	want := g.board
+
+	if err := g.save(); err != nil {
+		t.Fatal(err)
+	}
+	g.board = nil
+	loaded, err := g.load()
+	if err != nil || !loaded {
+		t.Fatalf("load = %v, %v", loaded, err)
+	}
+	b := g.board
+	if !sameSnapshot(b.snapshot(), want.snapshot()) {
+		t.Errorf("board = %+v, want %+v", b.snapshot(), want.snapshot())
+	}
+	if b.Seed() != want.Seed() || !b.begun.Equal(want.begun) {
+		t.Errorf("seed %d begun %v, want %d %v", b.Seed(), b.begun, want.Seed(), want.begun)
+	}
+	if len(b.history.undo) != len(want.history.undo) || len(b.history.redo) != 1 || b.history.used != 1 {
+		t.Errorf("history %d/%d used %d, want %d/1 used 1", len(b.history.undo), len(b.history.redo), b.history.used, len(want.history.undo))
+	}
+	if !reflect.DeepEqual(b.Replay().Moves, want.Replay().Moves) {
+		t.Errorf("replay = %v, want %v", b.Replay().Moves, want.Replay().Moves)
+	}
+	if g.best != 1234 || !g.keepPlaying {
+		t.Errorf("best %d keep playing %v", g.best, g.keepPlaying)
+	}
+	//读档后接着走的和没存档一样
+	if !b.Redo() || !want.Redo() || !sameSnapshot(b.snapshot(), want.snapshot()) {
+		t.Errorf("redo after load = %+v, want %+v", b.snapshot(), want.snapshot())
+	}
+}
+
+func TestSaveUpgrade(t *testing.T) {
+	g := testGame(t, Options{Seed: 3})
+	playMoves(t, g, 2)
+	data, err := g.encodeSave()
+	if err != nil {
+		t.Fatal(err)
+	}
+	//版本1的存档没有开局时间
+	var raw map[string]json.RawMessage
+	if err := json.Unmarshal(data, &raw); err != nil {
+		t.Fatal(err)
+	}
+	raw["version"] = json.RawMessage("1")
+	delete(raw, "begun")
+	old, _ := json.Marshal(raw)
+
+	f, err := decodeSave(old)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if f.Version != saveVersion || f.Begun.IsZero() {
+		t.Errorf("upgraded version %d begun %v", f.Version, f.Begun)
+	}
+	if !sameState(f.Board.State, g.board.State()) || len(f.Undo) != 2 {
+		t.Errorf("upgraded board lost state")
+	}
+}
+
+func TestLoadBadSave(t *testing.T) {
+	tests := []struct {
+		name   string
+		data   string
+		suffix string //改名后的后缀
+	}{
+		{"not json", "{", ".bad"},
+		{"unknown old version", `{"version": 0}`, ".bad"},
+		{"bad board", `{"version": 2, "board": {"state": {"width": 9, "height": 9, "cells": []}}}`, ".bad"},
+		{"newer version", `{"version": 99}`, ".v99"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			g := testGame(t, Options{})
+			path, err := configPath(saveFileName)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if err := os.WriteFile(path, []byte(tt.data), 0o644); err != nil {
+				t.Fatal(err)
+			}
+			loaded, err := g.load()
+			if loaded || err == nil {
+				t.Fatalf("load = %v, %v", loaded, err)
+			}
+			if !strings.HasPrefix(err.Error(), "twenty48: ") {
+				t.Errorf("error = %q", err)
+			}
+			if _, err := os.Stat(path); !os.IsNotExist(err) {
+				t.Errorf("save file still there: %v", err)
+			}
+			if data, err := os.ReadFile(path + tt.suffix); err != nil || string(data) != tt.data {
+				t.Errorf("renamed file = %q, %v", data, err)
+			}
+		})
+	}
+}
+
+func TestLoadNoSave(t *testing.T) {
+	g := testGame(t, Options{})
+	if loaded, err := g.load(); loaded || err != nil {
+		t.Errorf("load without a save = %v, %v", loaded, err)
+	}
+}
+
+func TestBestWithoutResume(t *testing.T) {
+	t.Setenv("XDG_CONFIG_HOME", t.TempDir())
+	g, err := NewGame(420, 600, Options{SkipTitle: true})
+	if err != nil {
+		t.Fatal(err)
+	}
+	g.best = 2048
+	if err := g.save(); err != nil {
+		t.Fatal(err)
+	}
+	//指定了棋盘时开始新的一局，最高分还在
+	for _, options := range []Options{{NoResume: true, Width: 5}, {Seed: 9}} {
+		options.SkipTitle = true
+		g, err := NewGame(420, 600, options)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if g.best != 2048 {
+			t.Errorf("%+v: best = %d, want 2048", options, g.best)
+		}
+		if err := g.save(); err != nil {
+			t.Fatal(err)
+		}
+	}
+}
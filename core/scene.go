@@ -0,0 +1,179 @@
+package core
+
+import (
+	"github.com/hajimehoshi/ebiten/v2"
+)
+
+const (
+	transitionFrames = 12 //切换场景的动画有几帧
+)
+
+// scene 一个画面 场景栈中只有最上面的场景接受输入，所有场景从下往上绘制
+// 切换场景的动画中只更新最底下的场景，input为nil
+type scene interface {
+	Update(input *Input) error
+	Draw(screen *ebiten.Image)
+}
+
+// transition 切换场景的动画
+type transition int
+
+const (
+	transitionNone  transition = iota //马上切换 例如刷新同一个画面
+	transitionFade                    //淡入淡出
+	transitionSlide                   //从下面滑入 离开时滑出到下面
+)
+
+// sceneStack 场景栈 最底下是棋盘，菜单和设置等画面压在上面
+// 切换的动画播放时不接受输入，避免连续点击穿过正在出现的画面，棋盘的动画照常播放
+type sceneStack struct {
+	scenes   []scene
+	entering scene      //正在出现的场景 没有时为nil
+	leaving  scene      //正在离开的场景 已经不在栈中 没有时为nil
+	kind     transition //正在播放的动画
+	frame    int        //动画还剩几帧
+	buffer   *ebiten.Image
+}
+
+// newSceneStack 初始化只有base的场景栈 base不会被弹出
+func newSceneStack(base scene) *sceneStack {
+	return &sceneStack{scenes: []scene{base}}
+}
+
+// top 最上面的场景
+func (s *sceneStack) top() scene {
+	return s.scenes[len(s.scenes)-1]
+}
+
+// push 把sc压到最上面
+func (s *sceneStack) push(sc scene, t transition) {
+	s.scenes = append(s.scenes, sc)
+	s.start(sc, nil, t)
+}
+
+// pop 弹出最上面的场景 只剩最底下的场景时什么都不做
+func (s *sceneStack) pop(t transition) {
+	if len(s.scenes) <= 1 {
+		return
+	}
+	top := s.top()
+	s.scenes = s.scenes[:len(s.scenes)-1]
+	s.start(nil, top, t)
+}
+
+// replace 用sc替换最上面的场景 只剩最底下的场景时压到上面
+func (s *sceneStack) replace(sc scene, t transition) {
+	if len(s.scenes) <= 1 {
+		s.push(sc, t)
+		return
+	}
+	top := s.top()
+	s.scenes[len(s.scenes)-1] = sc
+	s.start(sc, top, t)
+}
+
+// popAll 弹出最底下以外的所有场景 只有最上面的场景播放离开的动画
+func (s *sceneStack) popAll(t transition) {
+	if len(s.scenes) <= 1 {
+		return
+	}
+	top := s.top()
+	s.scenes = s.scenes[:1]
+	s.start(nil, top, t)
+}
+
+// start 开始播放动画 新的动画直接替换没有播放完的动画
+func (s *sceneStack) start(entering, leaving scene, t transition) {
+	s.entering, s.leaving, s.kind = entering, leaving, t
+	s.frame = transitionFrames
+	if t == transitionNone {
+		s.finish()
+	}
+}
+
+// finish 结束动画
+func (s *sceneStack) finish() {
+	s.entering, s.leaving = nil, nil
+	s.frame = 0
+}
+
+// Update 播放动画 动画中只用nil更新最底下的场景，结束后更新最上面的场景
+func (s *sceneStack) Update(input *Input) error {
+	if 0 < s.frame {
+		s.frame--
+		if s.frame == 0 {
+			s.finish()
+		}
+		return s.scenes[0].Update(nil)
+	}
+	return s.top().Update(input)
+}
+
+// Draw 从下往上绘制所有场景和正在离开的场景
+func (s *sceneStack) Draw(screen *ebiten.Image) {
+	//动画从0到1
+	t := 1 - float64(s.frame)/transitionFrames
+	for _, sc := range s.scenes {
+		if sc == s.entering {
+			s.drawTransition(screen, sc, t)
+		} else {
+			sc.Draw(screen)
+		}
+	}
+	if s.leaving != nil {
+		s.drawTransition(screen, s.leaving, 1-t)
+	}
+}
+
+// drawTransition 绘制正在切换的场景 t为0时完全看不见，为1时和平时一样
+func (s *sceneStack) drawTransition(screen *ebiten.Image, sc scene, t float64) {
+	w, h := screen.Bounds().Dx(), screen.Bounds().Dy()
+	if s.buffer == nil || s.buffer.Bounds().Dx() != w || s.buffer.Bounds().Dy() != h {
+		s.buffer = ebiten.NewImage(w, h)
+	}
+	s.buffer.Clear()
+	sc.Draw(s.buffer)
+	//先快后慢
+	t = 1 - (1-t)*(1-t)
+	op := &ebiten.DrawImageOptions{}
+	switch s.kind {
+	case transitionFade:
+		op.ColorScale.ScaleAlpha(float32(t))
+	case transitionSlide:
+		op.GeoM.Translate(0, (1-t)*float64(h))
+	}
+	screen.DrawImage(s.buffer, op)
+}
+
+// playScene 棋盘和分数 在场景栈的最底下
+type playScene struct {
+	g *Game
+}
+
+// Update 更新棋盘 input为nil时只播放动画
+func (s *playScene) Update(input *Input) error {
+	return s.g.updatePlay(input)
+}
+
+// Draw 绘制棋盘和分数
+func (s *playScene) Draw(screen *ebiten.Image) {
+	s.g.drawPlay(screen)
+}
+
+// open 用淡入的动画打开o 返回时回到现在的画面
+func (g *Game) open(o *overlay) error {
+	g.scenes.push(o, transitionFade)
+	return nil
+}
+
+// refresh 马上用o替换最上面的画面 例如修改设置后重新显示
+func (g *Game) refresh(o *overlay) error {
+	g.scenes.replace(o, transitionNone)
+	return nil
+}
+
+// back 关闭最上面的画面，回到上一个画面
+func (g *Game) back() error {
+	g.scenes.pop(transitionFade)
+	return nil
+}
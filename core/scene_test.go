@@ -0,0 +1,130 @@
+package core
+
+import (
+	"github.com/hajimehoshi/ebiten/v2"
+	"testing"
+)
+
+// fakeScene 记录收到的更新
+type fakeScene struct {
+	updates int //收到了几次更新
+	nils    int //其中input为nil的次数
+}
+
+func (s *fakeScene) Update(input *Input) error {
+	s.updates++
+	if input == nil {
+		s.nils++
+	}
+	return nil
+}
+
+func (s *fakeScene) Draw(screen *ebiten.Image) {}
+
+// runFrames 更新n帧
+func runFrames(t *testing.T, s *sceneStack, input *Input, n int) {
+	t.Helper()
+	for i := 0; i < n; i++ {
+		if err := s.Update(input); err != nil {
+			t.Fatal(err)
+		}
+	}
+}
+
+func TestSceneStack(t *testing.T) {
+	base, a, b := &fakeScene{}, &fakeScene{}, &fakeScene{}
+	s := newSceneStack(base)
+	//只剩最底下的场景时不能弹出
+	s.pop(transitionFade)
+	s.popAll(transitionFade)
+	if len(s.scenes) != 1 || s.top() != base || s.frame != 0 {
+		t.Fatalf("popped the base scene")
+	}
+
+	s.push(a, transitionNone)
+	if s.top() != a || s.entering != nil || s.frame != 0 {
+		t.Errorf("push without transition left %+v", s)
+	}
+	s.push(b, transitionFade)
+	if s.top() != b || s.entering != b || s.frame != transitionFrames {
+		t.Errorf("push with fade: top %v entering %v frame %d", s.top(), s.entering, s.frame)
+	}
+	s.replace(a, transitionSlide)
+	if len(s.scenes) != 3 || s.top() != a || s.entering != a || s.leaving != b || s.kind != transitionSlide {
+		t.Errorf("replace: %d scenes entering %v leaving %v", len(s.scenes), s.entering, s.leaving)
+	}
+	s.pop(transitionFade)
+	if len(s.scenes) != 2 || s.entering != nil || s.leaving != a {
+		t.Errorf("pop: %d scenes leaving %v", len(s.scenes), s.leaving)
+	}
+	s.push(b, transitionNone)
+	s.popAll(transitionFade)
+	if len(s.scenes) != 1 || s.top() != base || s.leaving != b {
+		t.Errorf("popAll: %d scenes leaving %v", len(s.scenes), s.leaving)
+	}
+
+	//只有最底下的场景时replace等于push
+	s.replace(b, transitionNone)
+	if len(s.scenes) != 2 || s.top() != b {
+		t.Errorf("replace on the base: %d scenes", len(s.scenes))
+	}
+}
+
+func TestSceneTransitionUpdates(t *testing.T) {
+	base, top := &fakeScene{}, &fakeScene{}
+	s := newSceneStack(base)
+	input := &Input{}
+	s.push(top, transitionFade)
+
+	//动画中最底下的场景继续更新，但是不接受输入
+	runFrames(t, s, input, transitionFrames)
+	if base.updates != transitionFrames || base.nils != transitionFrames {
+		t.Errorf("base got %d updates (%d nil), want %d nil", base.updates, base.nils, transitionFrames)
+	}
+	if top.updates != 0 {
+		t.Errorf("top got %d updates during the transition", top.updates)
+	}
+	if s.frame != 0 || s.entering != nil {
+		t.Fatalf("transition did not finish: frame %d", s.frame)
+	}
+
+	//动画结束后只有最上面的场景接受输入
+	runFrames(t, s, input, 3)
+	if top.updates != 3 || top.nils != 0 || base.updates != transitionFrames {
+		t.Errorf("after transition: top %d base %d", top.updates, base.updates)
+	}
+
+	//新的动画直接替换没有播放完的动画
+	s.pop(transitionFade)
+	runFrames(t, s, input, transitionFrames/2)
+	s.push(top, transitionSlide)
+	if s.frame != transitionFrames || s.leaving != nil || s.kind != transitionSlide {
+		t.Errorf("restarted transition: frame %d leaving %v", s.frame, s.leaving)
+	}
+}
+
+func TestTransitionKeepsBoardMoving(t *testing.T) {
+	g := testGame(t, Options{Seed: 1})
+	//种子1开局两个格子都在第一行，向下一定能移动
+	if err := g.board.Move(DirDown); err != nil {
+		t.Fatal(err)
+	}
+	g.open(g.pauseScene())
+	for i := 0; i < transitionFrames; i++ {
+		if err := g.scenes.Update(g.input); err != nil {
+			t.Fatal(err)
+		}
+	}
+	//动画中棋盘照常走完
+	for i := 0; i < 100 && !g.board.IsSettled(); i++ {
+		if err := g.play.Update(nil); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if !g.board.IsSettled() {
+		t.Fatal("the board did not settle")
+	}
+	if g.scenes.top() == g.play {
+		t.Errorf("the pause scene was closed")
+	}
+}
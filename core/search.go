@@ -0,0 +1,68 @@
+package core
+
+import (
+	"gameTest/ai"
+	"gameTest/engine"
+)
+
+// search 在后台运行的AI评估 Update只检查结果，搜索时画面不会卡住
+type search struct {
+	board  *Board
+	state  engine.State      //开始搜索时的棋盘 棋盘变了时丢弃结果
+	bits   engine.Bitboard   //开始搜索时的Bitboard 检查时比较转换后的数
+	packed bool              //bits是否有效 不是4x4的经典棋盘时逐个比较格子
+	result chan [4]ai.Choice //搜索完成后收到一次结果
+}
+
+// startSearch 在后台评估b现在的棋盘
+func startSearch(b *Board, opts ai.Options) *search {
+	s := &search{
+		board:  b,
+		state:  b.State(),
+		result: make(chan [4]ai.Choice, 1),
+	}
+	s.bits, s.packed = b.Bitboard()
+	go func() {
+		s.result <- ai.Evaluate(s.state, opts)
+	}()
+	return s
+}
+
+// poll 搜索完成时返回结果 还没有完成时返回false
+func (s *search) poll() ([4]ai.Choice, bool) {
+	select {
+	case choices := <-s.result:
+		return choices, true
+	default:
+		return [4]ai.Choice{}, false
+	}
+}
+
+// stale 开始搜索后棋盘是否换了或者走过
+func (s *search) stale(b *Board) bool {
+	if s.board != b {
+		return true
+	}
+	if s.packed {
+		bits, ok := b.Bitboard()
+		return !ok || bits != s.bits || s.state.Score() != b.Score()
+	}
+	return !sameState(s.state, b.State())
+}
+
+// sameState 两个棋盘的大小、分数和格子是否都一样
+func sameState(a, b engine.State) bool {
+	aw, ah := a.Size()
+	bw, bh := b.Size()
+	if aw != bw || ah != bh || a.Score() != b.Score() {
+		return false
+	}
+	for y := 0; y < ah; y++ {
+		for x := 0; x < aw; x++ {
+			if a.At(x, y) != b.At(x, y) {
+				return false
+			}
+		}
+	}
+	return true
+}
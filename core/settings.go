@@ -0,0 +1,320 @@
+package core
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"github.com/hajimehoshi/ebiten/v2"
+	"github.com/hajimehoshi/ebiten/v2/inpututil"
+	"log"
+	"os"
+)
+
+const (
+	settingsVersion  = 1               //设置文件的版本
+	settingsFileName = "settings.json" //设置的文件名
+)
+
+// settings 保存在配置目录的设置
+type settings struct {
+	Version      int    `json:"version"`
+	Theme        string `json:"theme"`                   //主题的名字 空为默认的主题
+	Palette      string `json:"palette,omitempty"`       //色弱配色的名字 空为主题的颜色
+	HighContrast bool   `json:"high_contrast,omitempty"` //高对比度
+	Patterns     bool   `json:"patterns,omitempty"`      //格子上画每个等级的图案
+	KeyScheme    string `json:"key_scheme,omitempty"`    //按键方案的名字 空为方向键
+	Keys         Keymap `json:"keys,omitempty"`          //自定义的按键 按键方案为custom时使用
+
+	Gamepads map[string]GamepadMap `json:"gamepads,omitempty"` //每种手柄的按钮绑定 键为SDL id
+}
+
+// newSettings 默认的设置
+func newSettings() *settings {
+	return &settings{
+		Version: settingsVersion,
+		Theme:   DefaultTheme,
+	}
+}
+
+// loadSettings 读取设置 没有文件时返回默认的设置
+// 文件损坏或版本不对时把它改名留着，返回默认的设置和错误
+func loadSettings() (*settings, error) {
+	path, err := configPath(settingsFileName)
+	if err != nil {
+		return newSettings(), err
+	}
+	data, err := os.ReadFile(path)
+	if errors.Is(err, os.ErrNotExist) {
+		return newSettings(), nil
+	}
+	if err != nil {
+		return newSettings(), err
+	}
+	s := newSettings()
+	if err := json.Unmarshal(data, s); err != nil {
+		os.Rename(path, path+".bad")
+		return newSettings(), fmt.Errorf("twenty48: corrupt settings: %w", err)
+	}
+	if s.Version != settingsVersion {
+		os.Rename(path, path+".bad")
+		return newSettings(), fmt.Errorf("twenty48: unsupported settings version %d", s.Version)
+	}
+	return s, nil
+}
+
+// save 保存设置
+func (s *settings) save() error {
+	path, err := configPath(settingsFileName)
+	if err != nil {
+		return err
+	}
+	data, err := json.MarshalIndent(s, "", "  ")
+	if err != nil {
+		return err
+	}
+	return writeFileAtomic(path, data)
+}
+
+// applyAccessibility 使用设置中的配色、高对比度和图案
+func (s *settings) applyAccessibility() {
+	access = accessibility{
+		palette:      paletteByName(s.Palette),
+		highContrast: s.HighContrast,
+		patterns:     s.Patterns,
+	}
+}
+
+// keymap 设置中的按键绑定 自定义的按键没有保存时使用方向键
+func (s *settings) keymap() Keymap {
+	if s.KeyScheme == customScheme && s.Keys != nil {
+		return s.Keys.clone()
+	}
+	if scheme, ok := keySchemeByName(s.KeyScheme); ok {
+		return scheme.keymap.clone()
+	}
+	return defaultKeymap()
+}
+
+// nextKeyScheme 切换到下一个预设的按键方案 有自定义的按键时也可以切换回自定义
+func (s *settings) nextKeyScheme() {
+	var names []string
+	for _, scheme := range keySchemes {
+		names = append(names, scheme.name)
+	}
+	if s.Keys != nil {
+		names = append(names, customScheme)
+	}
+	for i, n := range names {
+		if n == s.KeyScheme {
+			s.KeyScheme = names[(i+1)%len(names)]
+			return
+		}
+	}
+	//空为第一个方案
+	s.KeyScheme = names[1%len(names)]
+}
+
+// nextPalette 切换到下一个配色 最后一个之后回到主题的颜色
+func (s *settings) nextPalette() {
+	names := []string{""}
+	for _, p := range palettes {
+		names = append(names, p.name)
+	}
+	for i, n := range names {
+		if n == s.Palette {
+			s.Palette = names[(i+1)%len(names)]
+			return
+		}
+	}
+	s.Palette = ""
+}
+
+// loadTheme 开始时使用的主题 选项中指定的主题优先，不保存到设置
+// 设置中的主题读取失败时继续使用默认的主题
+func (g *Game) loadTheme() error {
+	if g.options.Theme != "" {
+		t, err := FindTheme(g.options.Theme)
+		if err != nil {
+			return err
+		}
+		return applyTheme(t)
+	}
+	if g.settings.Theme == "" || g.settings.Theme == theme.Name {
+		return nil
+	}
+	t, err := FindTheme(g.settings.Theme)
+	if err == nil {
+		err = applyTheme(t)
+	}
+	if err != nil {
+		log.Printf("读取主题失败: %v", err)
+	}
+	return nil
+}
+
+// settingsScene 设置画面 选择主题后马上切换并保存 message显示在最后一行
+func (g *Game) settingsScene(message string) *overlay {
+	themes, err := Themes()
+	if err != nil {
+		log.Printf("读取主题失败: %v", err)
+	}
+	paletteLabel := "主题"
+	if access.palette != nil {
+		paletteLabel = access.palette.label
+	}
+	lines := []string{
+		"主题\t" + theme.Name,
+		"配色\t" + paletteLabel,
+		"高对比度\t" + onOff(access.highContrast),
+		"图案\t" + onOff(access.patterns),
+	}
+	if message != "" {
+		lines = append(lines, message)
+	}
+	var buttons []*button
+	for _, t := range themes {
+		t := t
+		b := newButton(shorten(t.Name, 6), -1, func() error {
+			if err := applyTheme(t); err != nil {
+				log.Printf("切换主题失败: %v", err)
+				return g.refresh(g.settingsScene("切换失败"))
+			}
+			g.settings.Theme = t.Name
+			if err := g.settings.save(); err != nil {
+				log.Printf("保存设置失败: %v", err)
+			}
+			return g.refresh(g.settingsScene(""))
+		})
+		b.w = hudBoxWidth
+		b.disabled = t.Name == theme.Name
+		buttons = append(buttons, b)
+	}
+	//辅助显示的设置改变后马上生效并保存
+	change := func(f func(s *settings)) func() error {
+		return func() error {
+			f(g.settings)
+			g.settings.applyAccessibility()
+			if err := g.settings.save(); err != nil {
+				log.Printf("保存设置失败: %v", err)
+			}
+			return g.refresh(g.settingsScene(""))
+		}
+	}
+	buttons = append(buttons,
+		newButton("配色", ebiten.KeyC, change((*settings).nextPalette)),
+		newButton("高对比度", ebiten.KeyK, change(func(s *settings) { s.HighContrast = !s.HighContrast })),
+		newButton("图案", ebiten.KeyP, change(func(s *settings) { s.Patterns = !s.Patterns })),
+	)
+	buttons = append(buttons, newButton("按键", ebiten.KeyB, func() error {
+		return g.open(g.keySettingsScene(""))
+	}))
+	buttons = append(buttons, newBackButton("返回", g.back))
+	return newTextOverlay("设置", lines, 0, 0, g.ScreenWidth, g.ScreenHeight, buttons...)
+}
+
+// saveKeymap 使用并保存设置中的按键
+func (g *Game) saveKeymap() {
+	g.input.SetKeymap(g.settings.keymap())
+	if err := g.settings.save(); err != nil {
+		log.Printf("保存设置失败: %v", err)
+	}
+}
+
+// keySettingsScene 按键设置画面 显示每个操作绑定的按键，可以切换方案或者重新绑定
+func (g *Game) keySettingsScene(message string) *overlay {
+	m := g.input.Keymap()
+	lines := []string{"方案\t" + keySchemeLabel(g.settings.KeyScheme)}
+	//每行两个操作
+	for a := Action(0); a < actionCount; a += 2 {
+		lines = append(lines, fmt.Sprintf("%s\t%s\t%s\t%s",
+			actionLabels[a], m.keysLabel(a), actionLabels[a+1], m.keysLabel(a+1)))
+	}
+	if message != "" {
+		lines = append(lines, message)
+	}
+	var buttons []*button
+	for a := Action(0); a < actionCount; a++ {
+		a := a
+		b := newButton(actionLabels[a], -1, func() error {
+			return g.refresh(g.rebindScene(a))
+		})
+		b.w = sizeButtonWidth
+		buttons = append(buttons, b)
+	}
+	scheme := newButton("方案", ebiten.KeyTab, func() error {
+		g.settings.nextKeyScheme()
+		g.saveKeymap()
+		return g.refresh(g.keySettingsScene(""))
+	})
+	scheme.w = hudBoxWidth
+	reset := newButton("重置", -1, func() error {
+		g.settings.KeyScheme = ""
+		g.settings.Keys = nil
+		g.saveKeymap()
+		return g.refresh(g.keySettingsScene("已恢复默认按键"))
+	})
+	reset.w = hudBoxWidth
+	gamepad := newButton("手柄", ebiten.KeyG, func() error {
+		return g.open(g.gamepadSettingsScene(""))
+	})
+	gamepad.w = hudBoxWidth
+	back := newBackButton("返回", g.back)
+	back.w = hudBoxWidth
+	buttons = append(buttons, scheme, reset, gamepad, back)
+	return newTextOverlay("按键", lines, 0, 0, g.ScreenWidth, g.ScreenHeight, buttons...)
+}
+
+// rebindScene 等待按下a的新按键 按键原来绑定的操作会解除绑定
+// Esc取消，退格清除a所有的按键
+func (g *Game) rebindScene(a Action) *overlay {
+	lines := []string{
+		"当前\t" + g.input.Keymap().keysLabel(a),
+		"按下新的按键",
+		"Esc取消 退格清除",
+	}
+	//Esc由onUpdate处理，按钮只响应点击
+	cancel := newButton("取消", -1, func() error {
+		return g.refresh(g.keySettingsScene(""))
+	})
+	o := newTextOverlay(actionLabels[a], lines, 0, 0, g.ScreenWidth, g.ScreenHeight, cancel)
+	o.onUpdate = func() error {
+		keys := inpututil.AppendJustPressedKeys(nil)
+		if len(keys) == 0 {
+			return nil
+		}
+		key := keys[0]
+		if key == ebiten.KeyEscape {
+			return g.refresh(g.keySettingsScene(""))
+		}
+		//重新绑定时从当前的按键开始自定义
+		m := g.input.Keymap().clone()
+		message := ""
+		if key == ebiten.KeyBackspace {
+			m.Unbind(a)
+			message = actionLabels[a] + "已清除"
+		} else {
+			for other, ks := range m {
+				for _, k := range ks {
+					if k == key && other != a {
+						message = key.String() + "已从" + actionLabels[other] + "解除"
+					}
+				}
+			}
+			m.Unbind(a)
+			m.Bind(a, key)
+		}
+		g.settings.KeyScheme = customScheme
+		g.settings.Keys = m
+		g.saveKeymap()
+		return g.refresh(g.keySettingsScene(message))
+	}
+	return o
+}
+
+// onOff 开关显示的文字
+func onOff(on bool) string {
+	if on {
+		return "开"
+	}
+	return "关"
+}
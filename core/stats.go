@@ -0,0 +1,276 @@
+package core
+
+import (
+	"encoding/csv"
+	"fmt"
+	"gameTest/engine"
+	"github.com/hajimehoshi/ebiten/v2"
+	"github.com/hajimehoshi/ebiten/v2/vector"
+	"log"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+)
+
+const (
+	statsFileName   = "stats.csv" //导出的统计文件名
+	statsBarHeight  = 18          //条形图每一行的高度
+	statsLabelWidth = 60          //条形图左边标签的宽度
+	statsNoteWidth  = 90          //条形图右边说明的宽度
+	maxStatsTiles   = 8           //最多显示几种最大的格子
+)
+
+// profileStats 一个玩家所有局的统计
+type profileStats struct {
+	Games      int         `json:"games"`
+	TotalScore int         `json:"total_score"`
+	BestScore  int         `json:"best_score"`
+	MaxTiles   map[int]int `json:"max_tiles"` //每局最大的格子出现的次数
+	Moves      int         `json:"moves"`
+	Dirs       [4]int      `json:"dirs"` //按engine.Dirs的顺序每个方向走了几步
+	Time       int64       `json:"time"` //玩了多少毫秒
+}
+
+// add 记录一局
+func (s *profileStats) add(b *Board) {
+	s.Games++
+	s.TotalScore += b.Score()
+	if s.BestScore < b.Score() {
+		s.BestScore = b.Score()
+	}
+	if s.MaxTiles == nil {
+		s.MaxTiles = map[int]int{}
+	}
+	s.MaxTiles[b.State().MaxTile()]++
+	s.Moves += b.Moves()
+	if r := b.Replay(); r != nil {
+		for _, m := range r.Moves {
+			s.Dirs[m.Dir]++
+		}
+	}
+	s.Time += b.Duration().Milliseconds()
+}
+
+// average 平均分
+func (s *profileStats) average() int {
+	if s.Games == 0 {
+		return 0
+	}
+	return s.TotalScore / s.Games
+}
+
+// tiles 出现过的最大的格子 从小到大
+func (s *profileStats) tiles() []int {
+	tiles := make([]int, 0, len(s.MaxTiles))
+	for v := range s.MaxTiles {
+		tiles = append(tiles, v)
+	}
+	sort.Ints(tiles)
+	return tiles
+}
+
+// reached 最大的格子达到v的局数 用来计算每个目标的胜率
+func (s *profileStats) reached(v int) int {
+	n := 0
+	for t, c := range s.MaxTiles {
+		if v <= t {
+			n += c
+		}
+	}
+	return n
+}
+
+// stats 玩家的统计 没有时创建
+func (l *leaderboard) stats(profile string) *profileStats {
+	if l.Stats == nil {
+		l.Stats = map[string]*profileStats{}
+	}
+	s, ok := l.Stats[profile]
+	if !ok {
+		s = &profileStats{}
+		l.Stats[profile] = s
+	}
+	return s
+}
+
+// exportStats 把所有玩家的统计导出为csv 返回文件路径
+// 每个玩家一行，最大的格子每种值一列
+func (l *leaderboard) exportStats() (string, error) {
+	path, err := configPath(statsFileName)
+	if err != nil {
+		return "", err
+	}
+	var tiles []int
+	seen := map[int]bool{}
+	for _, s := range l.Stats {
+		for _, v := range s.tiles() {
+			if !seen[v] {
+				seen[v] = true
+				tiles = append(tiles, v)
+			}
+		}
+	}
+	sort.Ints(tiles)
+	header := []string{"profile", "games", "average_score", "best_score", "total_moves", "time_played_seconds"}
+	for _, d := range engine.Dirs {
+		header = append(header, "moves_"+strings.ToLower(d.String()))
+	}
+	for _, v := range tiles {
+		header = append(header, "max_tile_"+strconv.Itoa(v))
+	}
+	var sb strings.Builder
+	w := csv.NewWriter(&sb)
+	w.Write(header)
+	for _, p := range l.Profiles {
+		s, ok := l.Stats[p]
+		if !ok {
+			continue
+		}
+		row := []string{
+			p,
+			strconv.Itoa(s.Games),
+			strconv.Itoa(s.average()),
+			strconv.Itoa(s.BestScore),
+			strconv.Itoa(s.Moves),
+			strconv.FormatInt(s.Time/1000, 10),
+		}
+		for _, n := range s.Dirs {
+			row = append(row, strconv.Itoa(n))
+		}
+		for _, v := range tiles {
+			row = append(row, strconv.Itoa(s.MaxTiles[v]))
+		}
+		w.Write(row)
+	}
+	w.Flush()
+	if err := w.Error(); err != nil {
+		return "", err
+	}
+	return path, writeFileAtomic(path, []byte(sb.String()))
+}
+
+// recordStats 这局结束时记到当前玩家的统计 每局只记一次
+// 和排行榜一样不记录回放、AI玩过的和一步没走的局
+func (g *Game) recordStats() {
+	b := g.board
+	if g.recorded || g.playback != nil || g.assisted || b.Moves() == 0 {
+		return
+	}
+	g.recorded = true
+	l := g.leaderboard
+	l.stats(l.Profile).add(b)
+	if err := l.save(); err != nil {
+		log.Printf("保存统计失败: %v", err)
+	}
+}
+
+// bar 条形图的一行
+type bar struct {
+	label string
+	value int
+	note  string
+}
+
+// drawBarChart 绘制横向的条形图 左边是标签，右边是说明，条的长度按最大值缩放
+func drawBarChart(dst *ebiten.Image, bars []bar, x, y, w int) {
+	most := 0
+	for _, b := range bars {
+		if most < b.value {
+			most = b.value
+		}
+	}
+	width := w - statsLabelWidth - statsNoteWidth
+	for i, b := range bars {
+		by := y + i*statsBarHeight
+		drawTextCenter(dst, b.label, mplusTinyFont, x, by, statsLabelWidth, statsBarHeight, theme.Title)
+		if 0 < most && 0 < b.value {
+			bw := float32(width) * float32(b.value) / float32(most)
+			vector.DrawFilledRect(dst, float32(x+statsLabelWidth), float32(by+2), bw, statsBarHeight-4, theme.Button, false)
+		}
+		drawTextCenter(dst, b.note, mplusTinyFont, x+statsLabelWidth+width, by, statsNoteWidth, statsBarHeight, theme.Title)
+	}
+}
+
+// statsScene 当前玩家的统计画面 message显示在最后一行
+func (g *Game) statsScene(message string) *overlay {
+	l := g.leaderboard
+	s := l.stats(l.Profile)
+	lines := []string{"玩家\t" + l.Profile}
+	if s.Games == 0 {
+		lines = append(lines, "还没有记录")
+	} else {
+		lines = append(lines,
+			fmt.Sprintf("局数\t%d\t平均分\t%d", s.Games, s.average()),
+			fmt.Sprintf("最高分\t%d\t总步数\t%d", s.BestScore, s.Moves),
+			"游戏时间\t"+formatDuration(time.Duration(s.Time)*time.Millisecond),
+		)
+	}
+	if message != "" {
+		lines = append(lines, message)
+	}
+	profile := newButton("玩家:"+shorten(l.Profile, 4), ebiten.KeyP, func() error {
+		l.nextProfile()
+		if err := l.save(); err != nil {
+			log.Printf("保存排行榜失败: %v", err)
+		}
+		return g.refresh(g.statsScene(""))
+	})
+	profile.disabled = len(l.Profiles) <= 1
+	export := newButton("导出CSV", ebiten.KeyE, func() error {
+		path, err := l.exportStats()
+		if err != nil {
+			log.Printf("导出统计失败: %v", err)
+			return g.refresh(g.statsScene("导出失败"))
+		}
+		log.Printf("统计已导出: %s", path)
+		return g.refresh(g.statsScene("已导出 " + statsFileName))
+	})
+	back := newBackButton("返回", g.back)
+	o := newTextOverlay("统计", lines, 0, 0, g.ScreenWidth, g.ScreenHeight, profile, export, back)
+	if 0 < s.Games {
+		//最大的格子的分布 说明中是最大的格子至少达到这个值的比例，也就是以它为目标的胜率
+		tiles := s.tiles()
+		if maxStatsTiles < len(tiles) {
+			tiles = tiles[len(tiles)-maxStatsTiles:]
+		}
+		var tileBars []bar
+		for i := len(tiles) - 1; 0 <= i; i-- {
+			v := tiles[i]
+			tileBars = append(tileBars, bar{
+				label: strconv.Itoa(v),
+				value: s.MaxTiles[v],
+				note:  fmt.Sprintf("%d (%d%%)", s.MaxTiles[v], s.reached(v)*100/s.Games),
+			})
+		}
+		var dirBars []bar
+		for i, d := range engine.Dirs {
+			dirBars = append(dirBars, bar{
+				label: dirLabels[d],
+				value: s.Dirs[i],
+				note:  strconv.Itoa(s.Dirs[i]),
+			})
+		}
+		h := (len(tileBars)+len(dirBars)+2)*statsBarHeight + hudMargin
+		o.setBody(h, func(dst *ebiten.Image, x, y, w, h int) {
+			x += hudMargin
+			w -= 2 * hudMargin
+			drawTextCenter(dst, "最大的格子 (达到的比例)", mplusTinyFont, x, y, w, statsBarHeight, theme.Title)
+			y += statsBarHeight
+			drawBarChart(dst, tileBars, x, y, w)
+			y += len(tileBars)*statsBarHeight + hudMargin
+			drawTextCenter(dst, "每个方向的步数", mplusTinyFont, x, y, w, statsBarHeight, theme.Title)
+			y += statsBarHeight
+			drawBarChart(dst, dirBars, x, y, w)
+		})
+	}
+	return o
+}
+
+// dirLabels 方向显示的名字
+var dirLabels = map[Dir]string{
+	DirUp:    "上",
+	DirRight: "右",
+	DirDown:  "下",
+	DirLeft:  "左",
+}
@@ -0,0 +1,125 @@
+package core
+
+import (
+	"os"
+	"reflect"
+	"strconv"
+	"strings"
+	"testing"
+)
+
+func TestProfileStats(t *testing.T) {
+	g := testGame(t, Options{Seed: 3})
+	var s profileStats
+	if s.average() != 0 || len(s.tiles()) != 0 || s.reached(2) != 0 {
+		t.Errorf("empty stats = %d %v %d", s.average(), s.tiles(), s.reached(2))
+	}
+	playMoves(t, g, 4)
+	first := g.board
+	s.add(first)
+	if err := g.newGame(); err != nil {
+		t.Fatal(err)
+	}
+	playUntil(t, g, 16)
+	second := g.board
+	s.add(second)
+
+	if s.Games != 2 || s.Moves != first.Moves()+second.Moves() {
+		t.Errorf("games %d moves %d", s.Games, s.Moves)
+	}
+	if s.TotalScore != first.Score()+second.Score() || s.average() != s.TotalScore/2 {
+		t.Errorf("total %d average %d", s.TotalScore, s.average())
+	}
+	if s.BestScore != second.Score() {
+		t.Errorf("best %d, want %d", s.BestScore, second.Score())
+	}
+	dirs := 0
+	for _, n := range s.Dirs {
+		dirs += n
+	}
+	if dirs != s.Moves {
+		t.Errorf("%d moves by direction, want %d", dirs, s.Moves)
+	}
+	want := []int{first.State().MaxTile(), second.State().MaxTile()}
+	if want[0] == want[1] {
+		want = want[:1]
+	}
+	if got := s.tiles(); !reflect.DeepEqual(got, want) {
+		t.Errorf("tiles = %v, want %v", got, want)
+	}
+	if s.reached(2) != 2 || s.reached(16) != 1 || s.reached(1<<20) != 0 {
+		t.Errorf("reached 2/16/huge = %d/%d/%d", s.reached(2), s.reached(16), s.reached(1<<20))
+	}
+}
+
+func TestStatsEveryGameEnd(t *testing.T) {
+	g := testGame(t, Options{Seed: 4, WinTarget: 16})
+	stats := g.leaderboard.stats(g.leaderboard.Profile)
+	//一步没走的局不算
+	if err := g.newGame(); err != nil {
+		t.Fatal(err)
+	}
+	//放弃的局
+	playMoves(t, g, 3)
+	if err := g.newGame(); err != nil {
+		t.Fatal(err)
+	}
+	//赢了之后开始新的一局
+	playUntil(t, g, 16)
+	if err := g.newGame(); err != nil {
+		t.Fatal(err)
+	}
+	if stats.Games != 2 || stats.reached(16) != 1 {
+		t.Errorf("games %d won %d, want 2 and 1", stats.Games, stats.reached(16))
+	}
+	//统计已经保存
+	l, err := loadLeaderboard()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got := l.stats(l.Profile).Games; got != 2 {
+		t.Errorf("saved games = %d, want 2", got)
+	}
+}
+
+func TestExportStats(t *testing.T) {
+	g := testGame(t, Options{Seed: 5})
+	playMoves(t, g, 3)
+	g.recordStats()
+	l := g.leaderboard
+	s := l.stats(l.Profile)
+	path, err := l.exportStats()
+	if err != nil {
+		t.Fatal(err)
+	}
+	data, err := os.ReadFile(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	lines := strings.Split(strings.TrimSpace(string(data)), "\n")
+	if len(lines) != 2 {
+		t.Fatalf("csv has %d lines:\n%s", len(lines), data)
+	}
+	header := strings.Split(lines[0], ",")
+	row := strings.Split(lines[1], ",")
+	if len(header) != len(row) {
+		t.Fatalf("header has %d columns, row has %d", len(header), len(row))
+	}
+	want := map[string]string{
+		"profile":       l.Profile,
+		"games":         "1",
+		"average_score": strconv.Itoa(s.average()),
+		"best_score":    strconv.Itoa(s.BestScore),
+		"total_moves":   "3",
+		"max_tile_" + strconv.Itoa(g.board.State().MaxTile()): "1",
+	}
+	got := map[string]string{}
+	for i, h := range header {
+		got[h] = row[i]
+	}
+	for h, w := range want {
+		if got[h] != w {
+			t.Errorf("%s = %q, want %q", h, got[h], w)
+		}
+	}
+}
@@ -0,0 +1,363 @@
+package core
+
+import (
+	"embed"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"gameTest/fonts"
+	"github.com/BurntSushi/toml"
+	"golang.org/x/image/font/opentype"
+	"image/color"
+	"math"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+)
+
+const (
+	DefaultTheme = "classic" //默认的主题
+	themeDirName = "themes"  //玩家自己的主题放在配置目录下的这个目录
+)
+
+//go:embed themes
+var bundledThemes embed.FS
+
+// Theme 界面的颜色、字体和格子的圆角 可以从json或者toml文件读取
+type Theme struct {
+	Name         string     `json:"name" toml:"name"`
+	Background   hexColor   `json:"background" toml:"background"`       //画面的背景
+	Board        hexColor   `json:"board" toml:"board"`                 //棋盘的底色
+	Frame        hexColor   `json:"frame" toml:"frame"`                 //分数框
+	Empty        hexColor   `json:"empty" toml:"empty"`                 //空格子
+	Wall         hexColor   `json:"wall" toml:"wall"`                   //墙
+	WallInner    hexColor   `json:"wall_inner" toml:"wall_inner"`       //墙中间的框
+	Tiles        []hexColor `json:"tiles" toml:"tiles"`                 //每个等级的格子颜色 第一个为等级1 不够时自动生成
+	Texts        []hexColor `json:"texts" toml:"texts"`                 //每个等级的文字颜色 不够时按格子的亮度选择深色或浅色
+	Title        hexColor   `json:"title" toml:"title"`                 //标题和说明文字
+	Label        hexColor   `json:"label" toml:"label"`                 //分数框的标题
+	Value        hexColor   `json:"value" toml:"value"`                 //分数框的数字
+	Button       hexColor   `json:"button" toml:"button"`               //按钮
+	ButtonText   hexColor   `json:"button_text" toml:"button_text"`     //按钮的文字
+	Overlay      hexColor   `json:"overlay" toml:"overlay"`             //盖在棋盘上的画面
+	Accent       hexColor   `json:"accent" toml:"accent"`               //提示的箭头
+	Font         string     `json:"font" toml:"font"`                   //字体文件 空为内置的字体 相对路径相对于主题文件
+	CornerRadius float64    `json:"corner_radius" toml:"corner_radius"` //格子的圆角半径占格子大小的比例 0为直角
+}
+
+// hexColor 用"#rrggbb"或者"#rrggbbaa"表示的颜色
+type hexColor struct {
+	color.NRGBA
+	set bool //主题文件中是否写了这个颜色
+}
+
+// UnmarshalText 解析"#rrggbb"或者"#rrggbbaa" 其他写法都返回错误
+func (c *hexColor) UnmarshalText(text []byte) error {
+	s, ok := strings.CutPrefix(string(text), "#")
+	if !ok || len(s) != 6 && len(s) != 8 {
+		return fmt.Errorf("twenty48: invalid color %q", text)
+	}
+	v, err := hex.DecodeString(s)
+	if err != nil {
+		return fmt.Errorf("twenty48: invalid color %q", text)
+	}
+	a := uint8(0xff)
+	if len(v) == 4 {
+		a = v[3]
+	}
+	c.NRGBA = color.NRGBA{R: v[0], G: v[1], B: v[2], A: a}
+	c.set = true
+	return nil
+}
+
+// MarshalText 编码成"#rrggbb" 半透明时为"#rrggbbaa"
+func (c hexColor) MarshalText() ([]byte, error) {
+	if c.A == 0xff {
+		return []byte(fmt.Sprintf("#%02x%02x%02x", c.R, c.G, c.B)), nil
+	}
+	return []byte(fmt.Sprintf("#%02x%02x%02x%02x", c.R, c.G, c.B, c.A)), nil
+}
+
+// theme 当前使用的主题
+var theme = mustBundledTheme(DefaultTheme)
+
+// tileColor 等级为rank的格子的颜色 超出主题的等级时生成新的颜色
+func (t *Theme) tileColor(rank int) color.Color {
+	if rank < 1 {
+		return t.Empty
+	}
+	if rank <= len(t.Tiles) {
+		return t.Tiles[rank-1]
+	}
+	return generatedColor(t.Tiles, rank)
+}
+
+// textColor 等级为rank的格子的文字颜色
+func (t *Theme) textColor(rank int) color.Color {
+	if 1 <= rank && rank <= len(t.Texts) {
+		return t.Texts[rank-1]
+	}
+	return t.contrastText(t.tileColor(rank))
+}
+
+// contrastText 在bg上的文字颜色 按bg的亮度选择主题中最深或者最浅的文字颜色
+func (t *Theme) contrastText(bg color.Color) color.Color {
+	dark, light := t.Title.NRGBA, t.Title.NRGBA
+	for _, c := range append([]hexColor{t.ButtonText, t.Value}, t.Texts...) {
+		if luminance(c.NRGBA) < luminance(dark) {
+			dark = c.NRGBA
+		}
+		if luminance(light) < luminance(c.NRGBA) {
+			light = c.NRGBA
+		}
+	}
+	if 0.6 < luminance(color.NRGBAModel.Convert(bg).(color.NRGBA)) {
+		return dark
+	}
+	return light
+}
+
+// generatedColor 主题中没有的等级从最后一个颜色开始每级转动色相
+// 颜色一直不重复，不会因为值太大而没有颜色
+func generatedColor(tiles []hexColor, rank int) color.Color {
+	last := color.NRGBA{0xa3, 0x49, 0xa4, 0xff}
+	if 0 < len(tiles) {
+		last = tiles[len(tiles)-1].NRGBA
+	}
+	h, s, l := rgbToHSL(last)
+	n := rank - len(tiles)
+	//黄金角转动色相，亮度在一个范围内来回变化
+	h = math.Mod(h+float64(n)*137.508, 360)
+	l = 0.35 + 0.1*math.Mod(float64(n), 3)
+	if s < 0.4 {
+		s = 0.6
+	}
+	return hslToRGB(h, s, l)
+}
+
+// luminance 颜色的亮度 0到1
+func luminance(c color.NRGBA) float64 {
+	return (0.299*float64(c.R) + 0.587*float64(c.G) + 0.114*float64(c.B)) / 255
+}
+
+// withAlpha 把颜色的透明度改为a
+func withAlpha(c color.Color, a uint8) color.Color {
+	n := color.NRGBAModel.Convert(c).(color.NRGBA)
+	n.A = a
+	return n
+}
+
+// rgbToHSL 颜色转换为色相(0到360)、饱和度和亮度(0到1)
+func rgbToHSL(c color.NRGBA) (h, s, l float64) {
+	r, g, b := float64(c.R)/255, float64(c.G)/255, float64(c.B)/255
+	max := math.Max(r, math.Max(g, b))
+	min := math.Min(r, math.Min(g, b))
+	l = (max + min) / 2
+	if max == min {
+		return 0, 0, l
+	}
+	d := max - min
+	if 0.5 < l {
+		s = d / (2 - max - min)
+	} else {
+		s = d / (max + min)
+	}
+	switch max {
+	case r:
+		h = (g - b) / d
+		if g < b {
+			h += 6
+		}
+	case g:
+		h = (b-r)/d + 2
+	default:
+		h = (r-g)/d + 4
+	}
+	return h * 60, s, l
+}
+
+// hslToRGB 色相、饱和度和亮度转换为颜色
+func hslToRGB(h, s, l float64) color.NRGBA {
+	c := (1 - math.Abs(2*l-1)) * s
+	x := c * (1 - math.Abs(math.Mod(h/60, 2)-1))
+	m := l - c/2
+	var r, g, b float64
+	switch {
+	case h < 60:
+		r, g, b = c, x, 0
+	case h < 120:
+		r, g, b = x, c, 0
+	case h < 180:
+		r, g, b = 0, c, x
+	case h < 240:
+		r, g, b = 0, x, c
+	case h < 300:
+		r, g, b = x, 0, c
+	default:
+		r, g, b = c, 0, x
+	}
+	return color.NRGBA{
+		R: uint8(math.Round((r + m) * 255)),
+		G: uint8(math.Round((g + m) * 255)),
+		B: uint8(math.Round((b + m) * 255)),
+		A: 0xff,
+	}
+}
+
+// decodeTheme 按文件的扩展名解码json或者toml的主题
+func decodeTheme(name string, data []byte) (*Theme, error) {
+	t := &Theme{}
+	switch strings.ToLower(filepath.Ext(name)) {
+	case ".json":
+		if err := json.Unmarshal(data, t); err != nil {
+			return nil, fmt.Errorf("twenty48: invalid theme %s: %w", name, err)
+		}
+	case ".toml":
+		if err := toml.Unmarshal(data, t); err != nil {
+			return nil, fmt.Errorf("twenty48: invalid theme %s: %w", name, err)
+		}
+	default:
+		return nil, fmt.Errorf("twenty48: unknown theme format %s", name)
+	}
+	if t.Name == "" {
+		t.Name = strings.TrimSuffix(filepath.Base(name), filepath.Ext(name))
+	}
+	//没有这些颜色时棋盘和文字看不见
+	for _, c := range []struct {
+		key   string
+		color hexColor
+	}{{"board", t.Board}, {"frame", t.Frame}, {"empty", t.Empty}, {"title", t.Title}} {
+		if !c.color.set {
+			return nil, fmt.Errorf("twenty48: theme %s is missing %s", name, c.key)
+		}
+	}
+	if t.CornerRadius < 0 || 0.5 < t.CornerRadius {
+		return nil, fmt.Errorf("twenty48: invalid corner radius %g in theme %s", t.CornerRadius, name)
+	}
+	return t, nil
+}
+
+// LoadTheme 读取主题文件 字体的相对路径相对于主题文件
+func LoadTheme(path string) (*Theme, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+	t, err := decodeTheme(path, data)
+	if err != nil {
+		return nil, err
+	}
+	if t.Font != "" && !filepath.IsAbs(t.Font) {
+		t.Font = filepath.Join(filepath.Dir(path), t.Font)
+	}
+	return t, nil
+}
+
+// mustBundledTheme 内置的主题 找不到时panic
+func mustBundledTheme(name string) *Theme {
+	for _, t := range loadBundledThemes() {
+		if t.Name == name {
+			return t
+		}
+	}
+	panic("not reach")
+}
+
+// loadBundledThemes 读取所有内置的主题
+func loadBundledThemes() []*Theme {
+	entries, err := bundledThemes.ReadDir(themeDirName)
+	if err != nil {
+		panic(err)
+	}
+	var themes []*Theme
+	for _, e := range entries {
+		data, err := bundledThemes.ReadFile(themeDirName + "/" + e.Name())
+		if err != nil {
+			panic(err)
+		}
+		t, err := decodeTheme(e.Name(), data)
+		if err != nil {
+			panic(err)
+		}
+		themes = append(themes, t)
+	}
+	return themes
+}
+
+// Themes 内置的主题和配置目录下themes目录中的主题 按名字排序
+// 读取失败的主题文件会被跳过，返回遇到的第一个错误
+func Themes() ([]*Theme, error) {
+	themes := loadBundledThemes()
+	var firstErr error
+	dir, err := configPath(themeDirName)
+	if err != nil {
+		return themes, err
+	}
+	entries, err := os.ReadDir(dir)
+	if err != nil && !os.IsNotExist(err) {
+		firstErr = err
+	}
+	for _, e := range entries {
+		if e.IsDir() {
+			continue
+		}
+		t, err := LoadTheme(filepath.Join(dir, e.Name()))
+		if err != nil {
+			if firstErr == nil {
+				firstErr = err
+			}
+			continue
+		}
+		//和内置主题同名时替换内置的
+		replaced := false
+		for i, old := range themes {
+			if old.Name == t.Name {
+				themes[i] = t
+				replaced = true
+			}
+		}
+		if !replaced {
+			themes = append(themes, t)
+		}
+	}
+	sort.SliceStable(themes, func(i, j int) bool {
+		return themes[i].Name < themes[j].Name
+	})
+	return themes, firstErr
+}
+
+// FindTheme 根据名字找到主题 name也可以是主题文件的路径
+func FindTheme(name string) (*Theme, error) {
+	if strings.ContainsAny(name, `/\`) || filepath.Ext(name) != "" {
+		return LoadTheme(name)
+	}
+	themes, _ := Themes()
+	for _, t := range themes {
+		if t.Name == name {
+			return t, nil
+		}
+	}
+	return nil, fmt.Errorf("twenty48: unknown theme %q", name)
+}
+
+// applyTheme 切换到主题t 主题指定了字体时同时切换字体，否则使用内置的字体
+func applyTheme(t *Theme) error {
+	data := fonts.Shangshou
+	if t.Font != "" {
+		var err error
+		data, err = os.ReadFile(t.Font)
+		if err != nil {
+			return err
+		}
+	}
+	tt, err := opentype.Parse(data)
+	if err != nil {
+		return fmt.Errorf("twenty48: invalid font %s: %w", t.Font, err)
+	}
+	if err := setFont(tt); err != nil {
+		return err
+	}
+	theme = t
+	return nil
+}
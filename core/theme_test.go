@@ -0,0 +1,105 @@
+package core
+
+import (
+	"image/color"
+	"strings"
+	"testing"
+)
+
+func TestHexColor(t *testing.T) {
+	tests := []struct {
+		text string
+		want color.NRGBA
+		ok   bool
+	}{
+		{"#ff8000", color.NRGBA{0xff, 0x80, 0x00, 0xff}, true},
+		{"#FF8000", color.NRGBA{0xff, 0x80, 0x00, 0xff}, true},
+		{"#ff800080", color.NRGBA{0xff, 0x80, 0x00, 0x80}, true},
+		{"ff8000", color.NRGBA{}, false},
+		{"#ff80", color.NRGBA{}, false},
+		{"#ff80001", color.NRGBA{}, false},
+		{"#ff80zz", color.NRGBA{}, false},
+		{"#1g2233", color.NRGBA{}, false},
+		{"#+1+2+3", color.NRGBA{}, false},
+		{"# 1 2 3", color.NRGBA{}, false},
+		{"", color.NRGBA{}, false},
+	}
+	for _, tt := range tests {
+		var c hexColor
+		err := c.UnmarshalText([]byte(tt.text))
+		if (err == nil) != tt.ok {
+			t.Errorf("%q: err = %v, want ok %v", tt.text, err, tt.ok)
+			continue
+		}
+		if !tt.ok {
+			if !strings.HasPrefix(err.Error(), "twenty48: ") {
+				t.Errorf("%q: error = %q", tt.text, err)
+			}
+			continue
+		}
+		if c.NRGBA != tt.want || !c.set {
+			t.Errorf("%q = %v, want %v", tt.text, c.NRGBA, tt.want)
+		}
+		//编码后再解码得到同样的颜色
+		text, _ := c.MarshalText()
+		var back hexColor
+		if err := back.UnmarshalText(text); err != nil || back.NRGBA != c.NRGBA {
+			t.Errorf("%q round trip = %s %v", tt.text, text, err)
+		}
+	}
+}
+
+func TestBundledThemes(t *testing.T) {
+	names := map[string]bool{}
+	for _, th := range loadBundledThemes() {
+		names[th.Name] = true
+		for key, c := range map[string]hexColor{"board": th.Board, "frame": th.Frame, "empty": th.Empty, "title": th.Title} {
+			if !c.set {
+				t.Errorf("%s: %s is not set", th.Name, key)
+			}
+		}
+		if len(th.Tiles) == 0 {
+			t.Errorf("%s: no tile colors", th.Name)
+		}
+	}
+	for _, name := range []string{"classic", "dark", "ocean"} {
+		if !names[name] {
+			t.Errorf("bundled theme %s is missing", name)
+		}
+	}
+}
+
+func TestDecodeTheme(t *testing.T) {
+	const full = `{"board": "#bbada0", "frame": "#bbada0", "empty": "#cdc1b4", "title": "#776e65"}`
+	th, err := decodeTheme("custom.json", []byte(full))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if th.Name != "custom" || th.Board.NRGBA != (color.NRGBA{0xbb, 0xad, 0xa0, 0xff}) {
+		t.Errorf("decoded %s board %v", th.Name, th.Board.NRGBA)
+	}
+	tests := []struct {
+		name string
+		file string
+		data string
+		want string //错误中应该有的内容
+	}{
+		{"missing board", "a.json", `{"frame": "#000000", "empty": "#000000", "title": "#000000"}`, "missing board"},
+		{"missing title", "a.toml", "board = \"#000000\"\nframe = \"#000000\"\nempty = \"#000000\"\n", "missing title"},
+		{"bad color json", "a.json", `{"board": "#12345", "frame": "#000000", "empty": "#000000", "title": "#000000"}`, "invalid color"},
+		{"bad color toml", "a.toml", "board = \"#zzzzzz\"\n", "invalid color"},
+		{"corner radius", "a.json", full[:len(full)-1] + `, "corner_radius": 0.8}`, "corner radius"},
+		{"format", "a.yaml", full, "unknown theme format"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			_, err := decodeTheme(tt.file, []byte(tt.data))
+			if err == nil {
+				t.Fatal("decodeTheme succeeded")
+			}
+			if !strings.HasPrefix(err.Error(), "twenty48: ") || !strings.Contains(err.Error(), tt.want) {
+				t.Errorf("error = %q, want %q", err, tt.want)
+			}
+		})
+	}
+}
@@ -0,0 +1,38 @@
+package core
+
+import (
+	"github.com/hajimehoshi/ebiten/v2"
+	"strconv"
+)
+
+// titleScene 标题画面 盖住整个棋盘，resume为true时可以继续现在的一局
+func (g *Game) titleScene(resume bool) *overlay {
+	var lines []string
+	if 0 < g.best {
+		lines = append(lines, "最高分\t"+strconv.Itoa(g.best))
+	}
+	//手柄的暂停键也可以开始
+	label := "开始"
+	if resume {
+		label = "继续"
+	}
+	start := newActionButton(label, ActionPause, g.back)
+	start.key = ebiten.KeyEnter
+	menu := newButton("新游戏", ebiten.KeyN, func() error {
+		return g.open(g.menuScene())
+	})
+	leaderboard := newButton("排行榜", ebiten.KeyL, func() error {
+		return g.open(g.leaderboardScene(boardKey(g.board.State())))
+	})
+	stats := newButton("统计", ebiten.KeyS, func() error {
+		return g.open(g.statsScene(""))
+	})
+	settings := newButton("设置", ebiten.KeyO, func() error {
+		return g.open(g.settingsScene(""))
+	})
+	quit := newButton("退出", ebiten.KeyQ, g.quit)
+	o := newTextOverlay("2048", lines, 0, 0, g.ScreenWidth, g.ScreenHeight,
+		start, menu, leaderboard, stats, settings, quit)
+	o.opaque = true
+	return o
+}
@@ -1,11 +0,0 @@
-package core
-
-// 触摸的状态
-type touchState int
-
-const (
-	touchStateNone touchState = iota
-	touchStatePressing
-	touchStateSettled
-	touchStateInvalid
-)
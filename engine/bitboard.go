@@ -0,0 +1,278 @@
+package engine
+
+// Bitboard 4x4经典规则棋盘的紧凑表示，用来快速模拟
+// 每个格子用4位保存值的指数 0为空，1为2，15为32768
+// 第y行第x列的格子在第(x+4*y)个4位
+// 移动使用预先计算好的每一行的结果，不分配内存
+type Bitboard uint64
+
+const (
+	bitboardSize   = 4      //棋盘的宽高
+	maxBitboardExp = 15     //4位能保存的最大指数
+	rowMask        = 0xffff //一行的16位
+)
+
+var (
+	rowLeft  [1 << 16]uint16 //每一行向左移动后的结果
+	rowRight [1 << 16]uint16 //每一行向右移动后的结果
+	rowScore [1 << 16]int32  //每一行移动得到的分数 向左和向右一样
+)
+
+// 初始化每一行移动的结果
+func init() {
+	for row := 0; row < 1<<16; row++ {
+		line := unpackRow(uint16(row))
+		left, score := moveRow(line)
+		rowLeft[row] = packRow(left)
+		rowScore[row] = int32(score)
+		//向右移动等于把行反过来向左移动
+		rev := reverseRow(uint16(row))
+		rowRight[rev] = reverseRow(rowLeft[row])
+	}
+}
+
+// unpackRow 把一行拆成4个指数
+func unpackRow(row uint16) [bitboardSize]int {
+	var line [bitboardSize]int
+	for i := range line {
+		line[i] = int(row>>(4*i)) & 0xf
+	}
+	return line
+}
+
+// packRow 把4个指数合成一行
+func packRow(line [bitboardSize]int) uint16 {
+	var row uint16
+	for i, e := range line {
+		row |= uint16(e) << (4 * i)
+	}
+	return row
+}
+
+// reverseRow 把一行左右反过来
+func reverseRow(row uint16) uint16 {
+	return row>>12 | row>>4&0x00f0 | row<<4&0x0f00 | row<<12
+}
+
+// moveRow 一行向左移动 和经典规则的moveSegment一样从左边开始两两合并
+// 4位保存不了65536，两个32768的格子不能再合并，这时和State.Move的结果不同
+// NewBitboard不接受32768的格子，所以转换后的第一步总是和State.Move一样，
+// 只有搜索很多步之后才可能遇到，这时只是低估了那个棋盘
+func moveRow(line [bitboardSize]int) ([bitboardSize]int, int) {
+	var next [bitboardSize]int
+	var merged [bitboardSize]bool //这个位置是否是这一步合并得到的
+	score := 0
+	placed := 0
+	for _, e := range line {
+		if e == 0 {
+			continue
+		}
+		//和上一个放置的格子相同，并且上一个不是合并得到的
+		if 0 < placed && next[placed-1] == e && !merged[placed-1] && e < maxBitboardExp {
+			next[placed-1] = e + 1
+			merged[placed-1] = true
+			score += 1 << (e + 1)
+			continue
+		}
+		next[placed] = e
+		placed++
+	}
+	return next, score
+}
+
+// NewBitboard 把棋盘转换为Bitboard
+// 只支持4x4、没有墙、经典规则并且格子不超过16384的棋盘，其他棋盘返回false
+// 已经有32768的棋盘可能合并出Bitboard保存不了的65536，见moveRow
+func NewBitboard(s State) (Bitboard, bool) {
+	if s.width != bitboardSize || s.height != bitboardSize || s.Rule() != Classic {
+		return 0, false
+	}
+	var b Bitboard
+	for i, v := range s.cells {
+		if v == 0 {
+			continue
+		}
+		e, ok := exponent(v)
+		if !ok || maxBitboardExp <= e {
+			return 0, false
+		}
+		b |= Bitboard(e) << (4 * i)
+	}
+	return b, true
+}
+
+// exponent 2的几次方是v 墙和不是2的幂的值返回false
+func exponent(v int) (int, bool) {
+	if v < 2 || v&(v-1) != 0 {
+		return 0, false
+	}
+	e := 0
+	for 1 < v {
+		v >>= 1
+		e++
+	}
+	if maxBitboardExp < e {
+		return 0, false
+	}
+	return e, true
+}
+
+// State 转换为分数为score的经典规则棋盘
+func (b Bitboard) State(score int) State {
+	s := New(bitboardSize, bitboardSize)
+	for i := range s.cells {
+		s.cells[i] = b.value(i)
+	}
+	s.score = score
+	return s
+}
+
+// value 第i个格子的值
+func (b Bitboard) value(i int) int {
+	e := int(b>>(4*i)) & 0xf
+	if e == 0 {
+		return 0
+	}
+	return 1 << e
+}
+
+// At 该位置的值 空格子为0
+func (b Bitboard) At(x, y int) int {
+	if x < 0 || bitboardSize <= x || y < 0 || bitboardSize <= y {
+		panic("not reach")
+	}
+	return b.value(x + y*bitboardSize)
+}
+
+// Set 返回该位置设为v后的棋盘 v为0或者不超过32768的2的幂
+func (b Bitboard) Set(x, y, v int) Bitboard {
+	if x < 0 || bitboardSize <= x || y < 0 || bitboardSize <= y {
+		panic("not reach")
+	}
+	e := 0
+	if v != 0 {
+		var ok bool
+		if e, ok = exponent(v); !ok {
+			panic("not reach")
+		}
+	}
+	shift := 4 * (x + y*bitboardSize)
+	return b&^(0xf<<shift) | Bitboard(e)<<shift
+}
+
+// Tiles 棋盘上所有的格子 按行排列
+func (b Bitboard) Tiles() []Tile {
+	var tiles []Tile
+	for i := 0; i < bitboardSize*bitboardSize; i++ {
+		if v := b.value(i); v != 0 {
+			tiles = append(tiles, Tile{Pos: Pos{X: i % bitboardSize, Y: i / bitboardSize}, Value: v})
+		}
+	}
+	return tiles
+}
+
+// EmptyCount 空位置的个数
+func (b Bitboard) EmptyCount() int {
+	n := 0
+	for i := 0; i < bitboardSize*bitboardSize; i++ {
+		if b>>(4*i)&0xf == 0 {
+			n++
+		}
+	}
+	return n
+}
+
+// MaxTile 棋盘上最大的值
+func (b Bitboard) MaxTile() int {
+	m := 0
+	for i := 0; i < bitboardSize*bitboardSize; i++ {
+		if v := b.value(i); m < v {
+			m = v
+		}
+	}
+	return m
+}
+
+// Spawn 在随机的空位置增加一个格子
+// 和State.Spawn使用同样的随机数，同样的随机数得到同样的棋盘
+func (b Bitboard) Spawn(r Rand) (Bitboard, Tile, error) {
+	n := b.EmptyCount()
+	if n == 0 {
+		return b, Tile{}, ErrNoSpace
+	}
+	k := r.Intn(n)
+	v := spawnValue(Classic, r)
+	for i := 0; i < bitboardSize*bitboardSize; i++ {
+		if b>>(4*i)&0xf != 0 {
+			continue
+		}
+		if k == 0 {
+			p := Pos{X: i % bitboardSize, Y: i / bitboardSize}
+			return b.Set(p.X, p.Y, v), Tile{Pos: p, Value: v}, nil
+		}
+		k--
+	}
+	panic("not reach")
+}
+
+// Move 向dir方向移动，返回移动后的棋盘、得到的分数和是否移动了
+func (b Bitboard) Move(dir Dir) (Bitboard, int, bool) {
+	var next Bitboard
+	score := 0
+	switch dir {
+	case DirLeft, DirRight:
+		table := &rowLeft
+		if dir == DirRight {
+			table = &rowRight
+		}
+		for y := 0; y < bitboardSize; y++ {
+			row := b.Row(y)
+			next |= Bitboard(table[row]) << (16 * y)
+			score += int(rowScore[row])
+		}
+	case DirUp, DirDown:
+		//转置后每一列变成一行，向上等于向左
+		t := b.Transpose()
+		table := &rowLeft
+		if dir == DirDown {
+			table = &rowRight
+		}
+		for x := 0; x < bitboardSize; x++ {
+			row := t.Row(x)
+			next |= Bitboard(table[row]) << (16 * x)
+			score += int(rowScore[row])
+		}
+		next = next.Transpose()
+	default:
+		panic("not reach")
+	}
+	return next, score, next != b
+}
+
+// CanMove 是否还有可以移动的方向
+func (b Bitboard) CanMove() bool {
+	for _, d := range Dirs {
+		if _, _, ok := b.Move(d); ok {
+			return true
+		}
+	}
+	return false
+}
+
+// Row 第y行的16位 第x列在第x个4位
+func (b Bitboard) Row(y int) uint16 {
+	return uint16(b >> (16 * y) & rowMask)
+}
+
+// Transpose 行和列互换 第x列变成第x行
+func (b Bitboard) Transpose() Bitboard {
+	x := uint64(b)
+	a1 := x & 0xf0f00f0ff0f00f0f
+	a2 := x & 0x0000f0f00000f0f0
+	a3 := x & 0x0f0f00000f0f0000
+	a := a1 | a2<<12 | a3>>12
+	b1 := a & 0xff00ff0000ff00ff
+	b2 := a & 0x00ff00ff00000000
+	b3 := a & 0x00000000ff00ff00
+	return Bitboard(b1 | b2>>24 | b3<<24)
+}
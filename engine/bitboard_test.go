@@ -0,0 +1,119 @@
+package engine
+
+import (
+	"reflect"
+	"testing"
+)
+
+// randomBoard 随机的4x4经典棋盘 格子不超过maxExp次方
+func randomBoard(r *Source, maxExp int) State {
+	s := New(bitboardSize, bitboardSize)
+	for i := range s.cells {
+		//大约一半的位置为空，更容易出现移动和合并
+		if r.Intn(2) == 0 {
+			s.cells[i] = 1 << (1 + r.Intn(maxExp))
+		}
+	}
+	return s
+}
+
+func TestBitboardMoveMatchesState(t *testing.T) {
+	r := NewSource(14)
+	for i := 0; i < 2000; i++ {
+		//指数小的时候合并多，指数大的时候覆盖到接近上限的格子
+		maxExp := 3
+		if i%2 == 1 {
+			maxExp = maxBitboardExp - 1
+		}
+		s := randomBoard(r, maxExp)
+		b, ok := NewBitboard(s)
+		if !ok {
+			t.Fatalf("NewBitboard failed for %v", rowsOf(s))
+		}
+		if got := b.State(0); !reflect.DeepEqual(rowsOf(got), rowsOf(s)) {
+			t.Fatalf("State = %v, want %v", rowsOf(got), rowsOf(s))
+		}
+		for _, d := range Dirs {
+			want, result := s.Move(d)
+			next, score, moved := b.Move(d)
+			if moved != result.Moved || score != result.Score {
+				t.Fatalf("%v %v: bitboard moved %v score %d, state moved %v score %d", rowsOf(s), d, moved, score, result.Moved, result.Score)
+			}
+			if got := next.State(want.Score()); !reflect.DeepEqual(rowsOf(got), rowsOf(want)) {
+				t.Fatalf("%v %v: bitboard %v, state %v", rowsOf(s), d, rowsOf(got), rowsOf(want))
+			}
+		}
+		if b.CanMove() != s.CanMove() {
+			t.Fatalf("%v: CanMove differs", rowsOf(s))
+		}
+		if b.EmptyCount() != len(s.EmptyCells()) || b.MaxTile() != s.MaxTile() {
+			t.Fatalf("%v: EmptyCount or MaxTile differs", rowsOf(s))
+		}
+	}
+}
+
+func TestBitboardSpawnMatchesState(t *testing.T) {
+	s := grid(
+		[]int{2, 0, 4, 0},
+		[]int{0, 8, 0, 0},
+		[]int{0, 0, 0, 16},
+		[]int{2, 0, 0, 0},
+	)
+	b, _ := NewBitboard(s)
+	rs, rb := NewSource(3), NewSource(3)
+	for i := 0; i < 11; i++ {
+		var st, bt Tile
+		var err error
+		if s, st, err = s.Spawn(rs); err != nil {
+			t.Fatal(err)
+		}
+		if b, bt, err = b.Spawn(rb); err != nil {
+			t.Fatal(err)
+		}
+		if st != bt {
+			t.Fatalf("spawn #%d: state %+v, bitboard %+v", i, st, bt)
+		}
+	}
+	if _, _, err := b.Spawn(rb); err != ErrNoSpace {
+		t.Errorf("Spawn on a full board = %v, want %v", err, ErrNoSpace)
+	}
+}
+
+func TestNewBitboardRejects(t *testing.T) {
+	tests := []struct {
+		name  string
+		state State
+	}{
+		{"not 4x4", New(5, 4)},
+		{"wall", New(4, 4).Set(1, 1, Wall)},
+		{"other rule", New(4, 4).WithRule(Fibonacci)},
+		{"not a power of two", New(4, 4).Set(0, 0, 6)},
+		//两个32768会合并成保存不了的65536
+		{"reaches the cap", New(4, 4).Set(0, 0, 1<<maxBitboardExp)},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if _, ok := NewBitboard(tt.state); ok {
+				t.Errorf("NewBitboard succeeded")
+			}
+		})
+	}
+}
+
+func TestBitboardTranspose(t *testing.T) {
+	r := NewSource(2)
+	for i := 0; i < 100; i++ {
+		b, _ := NewBitboard(randomBoard(r, 10))
+		tb := b.Transpose()
+		for y := 0; y < bitboardSize; y++ {
+			for x := 0; x < bitboardSize; x++ {
+				if tb.At(y, x) != b.At(x, y) {
+					t.Fatalf("Transpose(%x) at (%d,%d) = %d, want %d", uint64(b), y, x, tb.At(y, x), b.At(x, y))
+				}
+			}
+		}
+		if tb.Transpose() != b {
+			t.Fatalf("Transpose twice = %x, want %x", uint64(tb.Transpose()), uint64(b))
+		}
+	}
+}
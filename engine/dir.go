@@ -0,0 +1,71 @@
+package engine
+
+import "fmt"
+
+// Dir represents a direction.
+type Dir int //方向
+
+const (
+	DirUp    Dir = iota //上
+	DirRight            //右
+	DirDown             //下
+	DirLeft             //左
+)
+
+// Dirs 所有的方向，按Dir的值排序
+var Dirs = [...]Dir{DirUp, DirRight, DirDown, DirLeft}
+
+// String returns a string representing the direction.
+func (d Dir) String() string {
+	switch d {
+	case DirUp:
+		return "Up"
+	case DirRight:
+		return "Right"
+	case DirDown:
+		return "Down"
+	case DirLeft:
+		return "Left"
+	}
+	panic("not reach")
+}
+
+// Vector returns a [-1, 1] value for each axis.
+func (d Dir) Vector() (x, y int) {
+	switch d {
+	case DirUp:
+		return 0, -1
+	case DirRight:
+		return 1, 0
+	case DirDown:
+		return 0, 1
+	case DirLeft:
+		return -1, 0
+	}
+	panic("not reach")
+}
+
+// ParseDir 把String返回的字符串转换成方向
+func ParseDir(s string) (Dir, error) {
+	for _, d := range Dirs {
+		if d.String() == s {
+			return d, nil
+		}
+	}
+	return 0, fmt.Errorf("twenty48: invalid direction %q", s)
+}
+
+// MarshalText 编码成String返回的字符串
+func (d Dir) MarshalText() ([]byte, error) {
+	return []byte(d.String()), nil
+}
+
+// UnmarshalText 从String返回的字符串解码
+func (d *Dir) UnmarshalText(text []byte) error {
+	v, err := ParseDir(string(text))
+	if err != nil {
+		return err
+	}
+	*d = v
+	return nil
+}
@@ -0,0 +1,46 @@
+package engine
+
+// 开始时放几个格子
+const startTiles = 2
+
+// Start 开始一局，在layout的空位置上随机放两个格子
+// 同样的layout和随机数得到同样的开局
+func Start(layout State, r Rand) (State, error) {
+	s := layout.Layout()
+	for i := 0; i < startTiles; i++ {
+		var err error
+		if s, _, err = s.Spawn(r); err != nil {
+			return s, err
+		}
+	}
+	return s, nil
+}
+
+// Step 走一步：移动后在空位置增加一个格子
+// 没有移动时不增加格子，返回的bool为false
+func Step(s State, dir Dir, r Rand) (State, MoveResult, bool) {
+	next, result := s.Move(dir)
+	if !result.Moved {
+		return s, result, false
+	}
+	//移动之后一定有空位置
+	next, _, err := next.Spawn(r)
+	if err != nil {
+		panic("not reach")
+	}
+	return next, result, true
+}
+
+// Play 用seed在layout上开局，按顺序走完moves
+// 同样的layout、seed和moves总是得到同样的结果，没有移动的步会被跳过
+func Play(layout State, seed uint64, moves []Dir) (State, error) {
+	r := NewSource(seed)
+	s, err := Start(layout, r)
+	if err != nil {
+		return s, err
+	}
+	for _, d := range moves {
+		s, _, _ = Step(s, d, r)
+	}
+	return s, nil
+}
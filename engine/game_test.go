@@ -0,0 +1,158 @@
+package engine
+
+import (
+	"encoding/json"
+	"reflect"
+	"testing"
+)
+
+// goldenMoves TestPlayGolden中走的步
+var goldenMoves = []Dir{DirLeft, DirUp, DirRight, DirDown, DirLeft, DirLeft, DirUp, DirUp}
+
+func TestSourceGolden(t *testing.T) {
+	r := NewSource(42)
+	want := []uint64{13679457532755275413, 2949826092126892291, 5139283748462763858}
+	for i, w := range want {
+		if got := r.Uint64(); got != w {
+			t.Errorf("Uint64 #%d = %d, want %d", i, got, w)
+		}
+	}
+	if r.Seed() != 42 {
+		t.Errorf("Seed = %d, want 42", r.Seed())
+	}
+}
+
+func TestSourceRestore(t *testing.T) {
+	r := NewSource(9)
+	r.Intn(10)
+	saved := r.State()
+	want := []int{r.Intn(100), r.Intn(100), r.Intn(100)}
+	r.SetState(saved)
+	got := []int{r.Intn(100), r.Intn(100), r.Intn(100)}
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("after SetState = %v, want %v", got, want)
+	}
+}
+
+func TestStartGolden(t *testing.T) {
+	s, err := Start(New(4, 4), NewSource(1))
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := [][]int{{2, 2, 0, 0}, {0, 0, 0, 0}, {0, 0, 0, 0}, {0, 0, 0, 0}}
+	if got := rowsOf(s); !reflect.DeepEqual(got, want) {
+		t.Errorf("board = %v, want %v", got, want)
+	}
+	//棋盘放不下两个格子
+	if _, err := Start(grid([]int{0, Wall}), NewSource(1)); err != ErrNoSpace {
+		t.Errorf("Start on a one-cell board = %v, want %v", err, ErrNoSpace)
+	}
+}
+
+func TestPlayGolden(t *testing.T) {
+	s, err := Play(New(4, 4), 1, goldenMoves)
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := [][]int{{4, 4, 2, 0}, {8, 0, 0, 0}, {2, 0, 0, 0}, {0, 0, 0, 0}}
+	if got := rowsOf(s); !reflect.DeepEqual(got, want) {
+		t.Errorf("board = %v, want %v", got, want)
+	}
+	if s.Score() != 16 {
+		t.Errorf("score = %d, want 16", s.Score())
+	}
+}
+
+func TestPlayMatchesStep(t *testing.T) {
+	r := NewSource(1)
+	s, err := Start(New(4, 4), r)
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, d := range goldenMoves {
+		next, result, ok := Step(s, d, r)
+		if ok != result.Moved {
+			t.Fatalf("Step %v ok = %v, Moved = %v", d, ok, result.Moved)
+		}
+		if !ok && !reflect.DeepEqual(rowsOf(next), rowsOf(s)) {
+			t.Fatalf("Step %v changed the board without moving", d)
+		}
+		s = next
+	}
+	played, _ := Play(New(4, 4), 1, goldenMoves)
+	if !reflect.DeepEqual(rowsOf(s), rowsOf(played)) || s.Score() != played.Score() {
+		t.Errorf("Step gave %v, Play gave %v", rowsOf(s), rowsOf(played))
+	}
+}
+
+func TestResumeFromJSON(t *testing.T) {
+	//一直走完和中途保存后恢复再走完得到同样的棋盘
+	layout := New(4, 4)
+	first, second := goldenMoves[:4], goldenMoves[4:]
+	r := NewSource(5)
+	s, err := Start(layout, r)
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, d := range first {
+		s, _, _ = Step(s, d, r)
+	}
+	data, err := json.Marshal(s)
+	if err != nil {
+		t.Fatal(err)
+	}
+	saved := r.State()
+	for _, d := range second {
+		s, _, _ = Step(s, d, r)
+	}
+
+	var restored State
+	if err := json.Unmarshal(data, &restored); err != nil {
+		t.Fatal(err)
+	}
+	r2 := NewSource(5)
+	r2.SetState(saved)
+	for _, d := range second {
+		restored, _, _ = Step(restored, d, r2)
+	}
+	if !reflect.DeepEqual(rowsOf(restored), rowsOf(s)) || restored.Score() != s.Score() {
+		t.Errorf("resumed game = %v (%d), want %v (%d)", rowsOf(restored), restored.Score(), rowsOf(s), s.Score())
+	}
+	played, _ := Play(layout, 5, goldenMoves)
+	if !reflect.DeepEqual(rowsOf(played), rowsOf(s)) {
+		t.Errorf("Play = %v, want %v", rowsOf(played), rowsOf(s))
+	}
+}
+
+func TestStateJSON(t *testing.T) {
+	s := grid([]int{2, Wall, 0}, []int{0, 8, 4}).WithRule(Fibonacci)
+	data, err := json.Marshal(s)
+	if err != nil {
+		t.Fatal(err)
+	}
+	var got State
+	if err := json.Unmarshal(data, &got); err != nil {
+		t.Fatal(err)
+	}
+	if !reflect.DeepEqual(rowsOf(got), rowsOf(s)) || got.Rule() != Fibonacci {
+		t.Errorf("round trip = %v %s, want %v %s", rowsOf(got), got.Rule().Name(), rowsOf(s), s.Rule().Name())
+	}
+	//只有size的旧格式
+	var old State
+	if err := json.Unmarshal([]byte(`{"size":2,"cells":[2,0,0,4],"score":8}`), &old); err != nil {
+		t.Fatal(err)
+	}
+	if w, h := old.Size(); w != 2 || h != 2 || old.Score() != 8 {
+		t.Errorf("old format = %dx%d score %d", w, h, old.Score())
+	}
+	for _, bad := range []string{
+		`{"width":2,"height":2,"cells":[2,0,0]}`,
+		`{"width":1,"height":1,"cells":[-5]}`,
+		`{"width":1,"height":1,"cells":[0],"score":-1}`,
+		`{"width":1,"height":1,"cells":[0],"rule":"nope"}`,
+	} {
+		if err := json.Unmarshal([]byte(bad), &old); err == nil {
+			t.Errorf("Unmarshal(%s) succeeded", bad)
+		}
+	}
+}
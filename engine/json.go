@@ -0,0 +1,62 @@
+package engine
+
+import (
+	"encoding/json"
+	"errors"
+)
+
+// stateJSON State的json格式
+type stateJSON struct {
+	Width  int    `json:"width"`
+	Height int    `json:"height"`
+	Size   int    `json:"size,omitempty"` //旧格式的正方形棋盘
+	Cells  []int  `json:"cells"`
+	Score  int    `json:"score"`
+	Rule   string `json:"rule,omitempty"` //合并规则的名字 空为classic
+}
+
+// MarshalJSON 把棋盘编码成json
+func (s State) MarshalJSON() ([]byte, error) {
+	return json.Marshal(stateJSON{
+		Width:  s.width,
+		Height: s.height,
+		Cells:  s.cells,
+		Score:  s.score,
+		Rule:   s.Rule().Name(),
+	})
+}
+
+// UnmarshalJSON 从json解码棋盘，格式不对时返回错误
+func (s *State) UnmarshalJSON(data []byte) error {
+	var v stateJSON
+	if err := json.Unmarshal(data, &v); err != nil {
+		return err
+	}
+	//兼容只有size的旧格式
+	if v.Width == 0 && v.Height == 0 {
+		v.Width, v.Height = v.Size, v.Size
+	}
+	if v.Width <= 0 || v.Height <= 0 || len(v.Cells) != v.Width*v.Height {
+		return errors.New("twenty48: invalid board size")
+	}
+	for _, c := range v.Cells {
+		if c < 0 && c != Wall {
+			return errors.New("twenty48: invalid tile value")
+		}
+	}
+	if v.Score < 0 {
+		return errors.New("twenty48: invalid score")
+	}
+	rule, err := RuleByName(v.Rule)
+	if err != nil {
+		return err
+	}
+	*s = State{
+		width:  v.Width,
+		height: v.Height,
+		cells:  v.Cells,
+		score:  v.Score,
+		rule:   rule,
+	}
+	return nil
+}
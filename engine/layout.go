@@ -0,0 +1,64 @@
+package engine
+
+import (
+	"fmt"
+	"strings"
+)
+
+const (
+	layoutEmpty = '.' //布局中的空位置
+	layoutWall  = '#' //布局中的墙
+)
+
+// ParseLayout 解析文字描述的棋盘布局
+// 每行是棋盘的一行，'.'是空位置，'#'是墙，空行会被忽略
+//
+//	.#..
+//	....
+//	..#.
+func ParseLayout(text string) (State, error) {
+	var rows []string
+	for _, line := range strings.Split(text, "\n") {
+		line = strings.TrimSpace(line)
+		if line == "" {
+			continue
+		}
+		rows = append(rows, line)
+	}
+	if len(rows) == 0 {
+		return State{}, fmt.Errorf("twenty48: empty layout")
+	}
+	width := len(rows[0])
+	s := New(width, len(rows))
+	for y, row := range rows {
+		if len(row) != width {
+			return State{}, fmt.Errorf("twenty48: layout row %d has %d cells, want %d", y+1, len(row), width)
+		}
+		for x, c := range row {
+			switch c {
+			case layoutEmpty:
+			case layoutWall:
+				s.cells[s.index(x, y)] = Wall
+			default:
+				return State{}, fmt.Errorf("twenty48: invalid layout cell %q at row %d", c, y+1)
+			}
+		}
+	}
+	return s, nil
+}
+
+// FormatLayout 把棋盘的墙写成ParseLayout可以解析的文字
+func FormatLayout(s State) string {
+	var sb strings.Builder
+	for y := 0; y < s.height; y++ {
+		for x := 0; x < s.width; x++ {
+			if s.IsWall(x, y) {
+				sb.WriteByte(layoutWall)
+			} else {
+				sb.WriteByte(layoutEmpty)
+			}
+		}
+		sb.WriteByte('\n')
+	}
+	return sb.String()
+}
@@ -0,0 +1,136 @@
+package engine
+
+import (
+	"reflect"
+	"strings"
+	"testing"
+)
+
+func TestMoveWalls(t *testing.T) {
+	tests := []struct {
+		name  string
+		start [][]int
+		dir   Dir
+		want  [][]int
+		score int
+	}{
+		{
+			name:  "stop at wall",
+			start: [][]int{{0, Wall, 0, 2}},
+			dir:   DirLeft,
+			want:  [][]int{{0, Wall, 2, 0}},
+		},
+		{
+			name:  "no merge through wall",
+			start: [][]int{{2, Wall, 2, 0}},
+			dir:   DirLeft,
+			want:  [][]int{{2, Wall, 2, 0}},
+		},
+		{
+			name:  "segments merge separately",
+			start: [][]int{{0, 2, 2, Wall, 4, 0, 4}},
+			dir:   DirRight,
+			want:  [][]int{{0, 0, 4, Wall, 0, 0, 8}},
+			score: 12,
+		},
+		{
+			name:  "vertical",
+			start: [][]int{{2}, {Wall}, {0}, {2}},
+			dir:   DirUp,
+			want:  [][]int{{2}, {Wall}, {2}, {0}},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			next, result := grid(tt.start...).Move(tt.dir)
+			if got := rowsOf(next); !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("board = %v, want %v", got, tt.want)
+			}
+			if moved := !reflect.DeepEqual(tt.start, tt.want); result.Moved != moved {
+				t.Errorf("Moved = %v, want %v", result.Moved, moved)
+			}
+			if result.Score != tt.score {
+				t.Errorf("score = %d, want %d", result.Score, tt.score)
+			}
+		})
+	}
+}
+
+func TestSpawnAvoidsWalls(t *testing.T) {
+	layout, err := ParseLayout(`
+		#.#
+		.#.
+		#.#
+	`)
+	if err != nil {
+		t.Fatal(err)
+	}
+	r := NewSource(3)
+	for i := 0; i < 200; i++ {
+		s, tile, err := layout.Spawn(r)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if layout.IsWall(tile.Pos.X, tile.Pos.Y) {
+			t.Fatalf("spawned on the wall at %v", tile.Pos)
+		}
+		if len(s.Walls()) != len(layout.Walls()) {
+			t.Fatalf("spawn changed the walls")
+		}
+	}
+	//只剩墙时不能再放
+	full := layout
+	for _, p := range layout.EmptyCells() {
+		full = full.Set(p.X, p.Y, 2)
+	}
+	if _, _, err := full.Spawn(r); err != ErrNoSpace {
+		t.Errorf("Spawn on a full board = %v, want %v", err, ErrNoSpace)
+	}
+}
+
+func TestLayoutRoundTrip(t *testing.T) {
+	text := ".#..\n....\n..#.\n"
+	s, err := ParseLayout(text)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if w, h := s.Size(); w != 4 || h != 3 {
+		t.Fatalf("size = %dx%d, want 4x3", w, h)
+	}
+	if got := s.Walls(); !reflect.DeepEqual(got, []Pos{{1, 0}, {2, 2}}) {
+		t.Errorf("walls = %v", got)
+	}
+	if got := FormatLayout(s); got != text {
+		t.Errorf("FormatLayout = %q, want %q", got, text)
+	}
+	//格子不写进布局 Layout只留下墙
+	played := s.Set(0, 0, 8)
+	if got := FormatLayout(played); got != text {
+		t.Errorf("FormatLayout with tiles = %q, want %q", got, text)
+	}
+	if got := played.Layout(); len(got.Tiles()) != 0 || !reflect.DeepEqual(got.Walls(), s.Walls()) {
+		t.Errorf("Layout kept tiles %v or lost walls", got.Tiles())
+	}
+}
+
+func TestParseLayoutErrors(t *testing.T) {
+	tests := []struct {
+		name string
+		text string
+	}{
+		{"empty", "\n  \n"},
+		{"ragged", "...\n..\n"},
+		{"bad cell", "..x\n...\n"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			_, err := ParseLayout(tt.text)
+			if err == nil {
+				t.Fatal("ParseLayout succeeded")
+			}
+			if !strings.HasPrefix(err.Error(), "twenty48: ") {
+				t.Errorf("error = %q", err)
+			}
+		})
+	}
+}
@@ -0,0 +1,142 @@
+package engine
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"os"
+	"time"
+)
+
+// ReplayVersion 录像文件的版本
+const ReplayVersion = 1
+
+// Replay 一局游戏的录像：种子和按顺序成功的移动
+type Replay struct {
+	Version int          `json:"version"`
+	Width   int          `json:"width"`
+	Height  int          `json:"height"`
+	Size    int          `json:"size,omitempty"` //旧格式的正方形棋盘
+	Walls   []Pos        `json:"walls,omitempty"`
+	Rule    string       `json:"rule,omitempty"` //合并规则的名字 空为classic
+	Seed    uint64       `json:"seed"`
+	Moves   []ReplayMove `json:"moves"`
+}
+
+// ReplayMove 录像中的一步
+type ReplayMove struct {
+	Dir  Dir   `json:"dir"`
+	Time int64 `json:"time"` //距离开局的毫秒数
+}
+
+// NewReplay 初始化在layout上开局的录像
+func NewReplay(layout State, seed uint64) *Replay {
+	w, h := layout.Size()
+	return &Replay{
+		Version: ReplayVersion,
+		Width:   w,
+		Height:  h,
+		Walls:   layout.Walls(),
+		Rule:    layout.Rule().Name(),
+		Seed:    seed,
+	}
+}
+
+// Layout 录像开局时的空棋盘
+func (r *Replay) Layout() State {
+	rule, err := RuleByName(r.Rule)
+	if err != nil {
+		panic("not reach")
+	}
+	s := New(r.Width, r.Height).WithRule(rule)
+	for _, p := range r.Walls {
+		s = s.Set(p.X, p.Y, Wall)
+	}
+	return s
+}
+
+// Record 记录一步 t为距离开局的时间
+func (r *Replay) Record(d Dir, t time.Duration) {
+	r.Moves = append(r.Moves, ReplayMove{Dir: d, Time: t.Milliseconds()})
+}
+
+// Dirs 录像中所有移动的方向
+func (r *Replay) Dirs() []Dir {
+	dirs := make([]Dir, len(r.Moves))
+	for i, m := range r.Moves {
+		dirs[i] = m.Dir
+	}
+	return dirs
+}
+
+// Seek 从开局走n步，返回棋盘和走完后的随机数
+// 用返回的随机数继续走可以得到和录像一样的结果
+func (r *Replay) Seek(n int) (State, *Source, error) {
+	if n < 0 || len(r.Moves) < n {
+		return State{}, nil, fmt.Errorf("twenty48: replay has no move %d", n)
+	}
+	src := NewSource(r.Seed)
+	s, err := Start(r.Layout(), src)
+	if err != nil {
+		return s, nil, err
+	}
+	for i, m := range r.Moves[:n] {
+		var moved bool
+		if s, _, moved = Step(s, m.Dir, src); !moved {
+			return s, nil, fmt.Errorf("twenty48: replay move %d (%s) does not move", i, m.Dir)
+		}
+	}
+	return s, src, nil
+}
+
+// WriteTo 把录像写成json
+func (r *Replay) WriteTo(w io.Writer) (int64, error) {
+	data, err := json.MarshalIndent(r, "", "  ")
+	if err != nil {
+		return 0, err
+	}
+	n, err := w.Write(data)
+	return int64(n), err
+}
+
+// ReadReplay 读取json格式的录像 检查每一步都能按录像走下去
+func ReadReplay(rd io.Reader) (*Replay, error) {
+	r := &Replay{}
+	if err := json.NewDecoder(rd).Decode(r); err != nil {
+		return nil, fmt.Errorf("twenty48: corrupt replay: %w", err)
+	}
+	if r.Version != ReplayVersion {
+		return nil, fmt.Errorf("twenty48: unsupported replay version %d", r.Version)
+	}
+	//兼容只有size的旧格式
+	if r.Width == 0 && r.Height == 0 {
+		r.Width, r.Height = r.Size, r.Size
+		r.Size = 0
+	}
+	if r.Width <= 0 || r.Height <= 0 {
+		return nil, fmt.Errorf("twenty48: invalid replay board size %dx%d", r.Width, r.Height)
+	}
+	if _, err := RuleByName(r.Rule); err != nil {
+		return nil, err
+	}
+	for _, p := range r.Walls {
+		if p.X < 0 || r.Width <= p.X || p.Y < 0 || r.Height <= p.Y {
+			return nil, fmt.Errorf("twenty48: replay wall (%d,%d) is outside the board", p.X, p.Y)
+		}
+	}
+	//每一步都必须能移动，否则回放会停在这一步
+	if _, _, err := r.Seek(len(r.Moves)); err != nil {
+		return nil, err
+	}
+	return r, nil
+}
+
+// ReadReplayFile 从文件读取录像
+func ReadReplayFile(path string) (*Replay, error) {
+	f, err := os.Open(path)
+	if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+	return ReadReplay(f)
+}
@@ -0,0 +1,110 @@
+package engine
+
+import (
+	"bytes"
+	"reflect"
+	"strings"
+	"testing"
+	"time"
+)
+
+// testReplay 用seed在layout上走dirs，只记录能移动的步
+func testReplay(layout State, seed uint64, dirs []Dir) (*Replay, State) {
+	r := NewReplay(layout, seed)
+	src := NewSource(seed)
+	s, err := Start(layout, src)
+	if err != nil {
+		panic(err)
+	}
+	for i, d := range dirs {
+		var moved bool
+		if s, _, moved = Step(s, d, src); moved {
+			r.Record(d, time.Duration(i)*time.Second)
+		}
+	}
+	return r, s
+}
+
+func TestReplayRoundTrip(t *testing.T) {
+	layout := New(5, 3).Set(2, 1, Wall).WithRule(Fibonacci)
+	r, want := testReplay(layout, 8, goldenMoves)
+	var buf bytes.Buffer
+	if _, err := r.WriteTo(&buf); err != nil {
+		t.Fatal(err)
+	}
+	got, err := ReadReplay(&buf)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !reflect.DeepEqual(got, r) {
+		t.Errorf("read %+v, want %+v", got, r)
+	}
+	s, err := Play(got.Layout(), got.Seed, got.Dirs())
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !reflect.DeepEqual(rowsOf(s), rowsOf(want)) || s.Score() != want.Score() {
+		t.Errorf("replayed %v, want %v", rowsOf(s), rowsOf(want))
+	}
+}
+
+func TestReplaySeek(t *testing.T) {
+	r, _ := testReplay(New(4, 4), 1, goldenMoves)
+	for n := 0; n <= len(r.Moves); n++ {
+		s, src, err := r.Seek(n)
+		if err != nil {
+			t.Fatalf("Seek(%d): %v", n, err)
+		}
+		want, _ := Play(New(4, 4), 1, r.Dirs()[:n])
+		if !reflect.DeepEqual(rowsOf(s), rowsOf(want)) {
+			t.Errorf("Seek(%d) = %v, want %v", n, rowsOf(s), rowsOf(want))
+		}
+		//从返回的随机数接着走和一直走下去一样
+		if n < len(r.Moves) {
+			next, _, _ := Step(s, r.Moves[n].Dir, src)
+			want, _ := Play(New(4, 4), 1, r.Dirs()[:n+1])
+			if !reflect.DeepEqual(rowsOf(next), rowsOf(want)) {
+				t.Errorf("step after Seek(%d) = %v, want %v", n, rowsOf(next), rowsOf(want))
+			}
+		}
+	}
+	for _, n := range []int{-1, len(r.Moves) + 1} {
+		if _, _, err := r.Seek(n); err == nil {
+			t.Errorf("Seek(%d) succeeded", n)
+		}
+	}
+}
+
+func TestReadReplayErrors(t *testing.T) {
+	tests := []struct {
+		name string
+		json string
+	}{
+		{"not json", `{`},
+		{"version", `{"version": 2, "width": 4, "height": 4}`},
+		{"size", `{"version": 1, "width": 0, "height": 4}`},
+		{"rule", `{"version": 1, "width": 4, "height": 4, "rule": "nope"}`},
+		{"wall", `{"version": 1, "width": 4, "height": 4, "walls": [{"x": 4, "y": 0}]}`},
+		//种子1的开局两个格子都在第一行，向上不能移动
+		{"move does not move", `{"version": 1, "width": 4, "height": 4, "seed": 1, "moves": [{"dir": "Up", "time": 0}]}`},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			_, err := ReadReplay(strings.NewReader(tt.json))
+			if err == nil {
+				t.Fatal("ReadReplay succeeded")
+			}
+			if !strings.HasPrefix(err.Error(), "twenty48: ") {
+				t.Errorf("error = %q", err)
+			}
+		})
+	}
+	//只有size的旧格式
+	r, err := ReadReplay(strings.NewReader(`{"version": 1, "size": 3, "seed": 1, "moves": []}`))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if r.Width != 3 || r.Height != 3 || r.Size != 0 {
+		t.Errorf("old format = %dx%d size %d", r.Width, r.Height, r.Size)
+	}
+}
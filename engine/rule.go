@@ -0,0 +1,211 @@
+package engine
+
+import "fmt"
+
+// MergeRule 合并规则 决定哪些格子可以合并、新格子的值和胜利的目标
+type MergeRule interface {
+	// Name 规则的名字 用于存档和命令行
+	Name() string
+	// GroupSize 几个格子合并成一个
+	GroupSize() int
+	// Merge GroupSize个相邻的格子能否合并，返回合并后的值
+	// values按移动方向排列，第一个离目的地最近
+	Merge(values []int) (int, bool)
+	// SpawnWeights 新格子可能的值和权重
+	SpawnWeights() []SpawnWeight
+	// Target 默认的胜利目标
+	Target() int
+	// Rank 值在这个规则的数列中是第几个 最小的值为1 用来决定格子的颜色
+	Rank(value int) int
+}
+
+// SpawnWeight 新格子的值和出现的权重
+type SpawnWeight struct {
+	Value  int
+	Weight int
+}
+
+var (
+	Classic       MergeRule = classicRule{}   //经典2048 相同的两个格子合并成它们的和
+	Fibonacci     MergeRule = fibonacciRule{} //相邻的两个斐波那契数合并成它们的和
+	PowersOfThree MergeRule = powersOfThree{} //相同的三个格子合并成它们的和
+	Threes        MergeRule = threesRule{}    //1和2合并成3，3以上相同的两个格子合并
+)
+
+// Rules 所有的合并规则
+var Rules = []MergeRule{Classic, Fibonacci, PowersOfThree, Threes}
+
+// RuleByName 根据名字找到合并规则 空字符串为Classic
+func RuleByName(name string) (MergeRule, error) {
+	if name == "" {
+		return Classic, nil
+	}
+	for _, r := range Rules {
+		if r.Name() == name {
+			return r, nil
+		}
+	}
+	return nil, fmt.Errorf("twenty48: unknown merge rule %q", name)
+}
+
+// spawnValue 按规则的权重随机一个新格子的值
+func spawnValue(rule MergeRule, r Rand) int {
+	ws := rule.SpawnWeights()
+	total := 0
+	for _, w := range ws {
+		total += w.Weight
+	}
+	n := r.Intn(total)
+	for _, w := range ws {
+		if n < w.Weight {
+			return w.Value
+		}
+		n -= w.Weight
+	}
+	panic("not reach")
+}
+
+// rankOf 值在数列中是第几个 不在数列中时返回0
+func rankOf(seq []int, value int) int {
+	for i, v := range seq {
+		if v == value {
+			return i + 1
+		}
+	}
+	return 0
+}
+
+// geometric 从first开始每次乘ratio的数列
+func geometric(first, ratio, n int) []int {
+	seq := make([]int, n)
+	v := first
+	for i := range seq {
+		seq[i] = v
+		v *= ratio
+	}
+	return seq
+}
+
+type classicRule struct{}
+
+func (classicRule) Name() string   { return "classic" }
+func (classicRule) GroupSize() int { return 2 }
+func (classicRule) Target() int    { return 2048 }
+
+func (classicRule) Merge(values []int) (int, bool) {
+	if values[0] != values[1] {
+		return 0, false
+	}
+	return values[0] + values[1], true
+}
+
+// SpawnWeights 2为9/10 4为1/10
+// 4放在前面，和之前 rand.Intn(10) == 0 为4 的结果一样
+func (classicRule) SpawnWeights() []SpawnWeight {
+	return []SpawnWeight{{Value: 4, Weight: 1}, {Value: 2, Weight: 9}}
+}
+
+func (classicRule) Rank(value int) int {
+	r := 0
+	for 1 < value {
+		value >>= 1
+		r++
+	}
+	return r
+}
+
+// fibonacciSeq 斐波那契数列 从1, 2开始
+var fibonacciSeq = func() []int {
+	seq := []int{1, 2}
+	for len(seq) < 40 {
+		seq = append(seq, seq[len(seq)-1]+seq[len(seq)-2])
+	}
+	return seq
+}()
+
+type fibonacciRule struct{}
+
+func (fibonacciRule) Name() string   { return "fibonacci" }
+func (fibonacciRule) GroupSize() int { return 2 }
+func (fibonacciRule) Target() int    { return 2584 }
+
+// Merge 两个1，或者数列中相邻的两个数可以合并
+func (fibonacciRule) Merge(values []int) (int, bool) {
+	a, b := values[0], values[1]
+	if a == 1 && b == 1 {
+		return 2, true
+	}
+	ra, rb := rankOf(fibonacciSeq, a), rankOf(fibonacciSeq, b)
+	if ra == 0 || rb == 0 || abs(ra-rb) != 1 {
+		return 0, false
+	}
+	return a + b, true
+}
+
+func (fibonacciRule) SpawnWeights() []SpawnWeight {
+	return []SpawnWeight{{Value: 2, Weight: 1}, {Value: 1, Weight: 9}}
+}
+
+func (fibonacciRule) Rank(value int) int {
+	return rankOf(fibonacciSeq, value)
+}
+
+// powersOfThreeSeq 3的幂
+var powersOfThreeSeq = geometric(3, 3, 30)
+
+type powersOfThree struct{}
+
+func (powersOfThree) Name() string   { return "pow3" }
+func (powersOfThree) GroupSize() int { return 3 }
+func (powersOfThree) Target() int    { return 2187 }
+
+func (powersOfThree) Merge(values []int) (int, bool) {
+	if values[0] != values[1] || values[1] != values[2] {
+		return 0, false
+	}
+	return values[0] * 3, true
+}
+
+func (powersOfThree) SpawnWeights() []SpawnWeight {
+	return []SpawnWeight{{Value: 9, Weight: 1}, {Value: 3, Weight: 9}}
+}
+
+func (powersOfThree) Rank(value int) int {
+	return rankOf(powersOfThreeSeq, value)
+}
+
+// threesSeq 1, 2, 3, 6, 12, 24...
+var threesSeq = append([]int{1, 2}, geometric(3, 2, 30)...)
+
+type threesRule struct{}
+
+func (threesRule) Name() string   { return "threes" }
+func (threesRule) GroupSize() int { return 2 }
+func (threesRule) Target() int    { return 768 }
+
+// Merge 1和2合并成3，3以上相同的两个格子合并
+func (threesRule) Merge(values []int) (int, bool) {
+	a, b := values[0], values[1]
+	switch {
+	case a+b == 3 && a != b:
+		return 3, true
+	case 3 <= a && a == b:
+		return a + b, true
+	}
+	return 0, false
+}
+
+func (threesRule) SpawnWeights() []SpawnWeight {
+	return []SpawnWeight{{Value: 3, Weight: 2}, {Value: 2, Weight: 4}, {Value: 1, Weight: 4}}
+}
+
+func (threesRule) Rank(value int) int {
+	return rankOf(threesSeq, value)
+}
+
+func abs(x int) int {
+	if x < 0 {
+		return -x
+	}
+	return x
+}
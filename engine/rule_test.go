@@ -0,0 +1,150 @@
+package engine
+
+import (
+	"reflect"
+	"testing"
+)
+
+func TestRuleMerge(t *testing.T) {
+	tests := []struct {
+		rule   MergeRule
+		values []int
+		want   int
+		ok     bool
+	}{
+		{Classic, []int{2, 2}, 4, true},
+		{Classic, []int{2, 4}, 0, false},
+		{Fibonacci, []int{1, 1}, 2, true},
+		{Fibonacci, []int{1, 2}, 3, true},
+		{Fibonacci, []int{5, 3}, 8, true},
+		{Fibonacci, []int{2, 2}, 0, false},
+		{Fibonacci, []int{3, 8}, 0, false},
+		{Fibonacci, []int{4, 5}, 0, false},
+		{PowersOfThree, []int{3, 3, 3}, 9, true},
+		{PowersOfThree, []int{9, 9, 9}, 27, true},
+		{PowersOfThree, []int{3, 3, 9}, 0, false},
+		{Threes, []int{1, 2}, 3, true},
+		{Threes, []int{2, 1}, 3, true},
+		{Threes, []int{1, 1}, 0, false},
+		{Threes, []int{2, 2}, 0, false},
+		{Threes, []int{3, 3}, 6, true},
+		{Threes, []int{6, 3}, 0, false},
+	}
+	for _, tt := range tests {
+		got, ok := tt.rule.Merge(tt.values)
+		if got != tt.want || ok != tt.ok {
+			t.Errorf("%s Merge(%v) = %d, %v, want %d, %v", tt.rule.Name(), tt.values, got, ok, tt.want, tt.ok)
+		}
+	}
+}
+
+func TestRuleMove(t *testing.T) {
+	tests := []struct {
+		rule  MergeRule
+		start []int
+		want  []int
+		score int
+	}{
+		{Fibonacci, []int{1, 1, 2, 0}, []int{2, 2, 0, 0}, 2},
+		{Fibonacci, []int{2, 3, 5, 0}, []int{5, 5, 0, 0}, 5},
+		{Fibonacci, []int{2, 5, 0, 0}, []int{2, 5, 0, 0}, 0},
+		{PowersOfThree, []int{3, 3, 3, 3}, []int{9, 3, 0, 0}, 9},
+		{PowersOfThree, []int{3, 3, 0, 9}, []int{3, 3, 9, 0}, 0},
+		{PowersOfThree, []int{3, 0, 3, 3}, []int{9, 0, 0, 0}, 9},
+		{Threes, []int{1, 2, 1, 2}, []int{3, 3, 0, 0}, 6},
+		{Threes, []int{1, 1, 3, 3}, []int{1, 1, 6, 0}, 6},
+	}
+	for _, tt := range tests {
+		s := grid(tt.start).WithRule(tt.rule)
+		next, result := s.Move(DirLeft)
+		if got := rowsOf(next)[0]; !reflect.DeepEqual(got, tt.want) {
+			t.Errorf("%s %v left = %v, want %v", tt.rule.Name(), tt.start, got, tt.want)
+		}
+		if result.Score != tt.score {
+			t.Errorf("%s %v score = %d, want %d", tt.rule.Name(), tt.start, result.Score, tt.score)
+		}
+		if next.Rule() != tt.rule {
+			t.Errorf("Move changed the rule to %s", next.Rule().Name())
+		}
+	}
+}
+
+func TestRuleSpawn(t *testing.T) {
+	tests := []struct {
+		rule   MergeRule
+		values []int
+		target int
+	}{
+		{Classic, []int{2, 4}, 2048},
+		{Fibonacci, []int{1, 2}, 2584},
+		{PowersOfThree, []int{3, 9}, 2187},
+		{Threes, []int{1, 2, 3}, 768},
+	}
+	for _, tt := range tests {
+		t.Run(tt.rule.Name(), func(t *testing.T) {
+			if got := tt.rule.Target(); got != tt.target {
+				t.Errorf("Target = %d, want %d", got, tt.target)
+			}
+			if tt.rule.Rank(tt.rule.Target()) == 0 {
+				t.Errorf("Target %d is not in the sequence", tt.rule.Target())
+			}
+			//足够多次之后每个值都出现过，且不出现别的值
+			seen := map[int]bool{}
+			r := NewSource(11)
+			for i := 0; i < 1000; i++ {
+				seen[spawnValue(tt.rule, r)] = true
+			}
+			want := map[int]bool{}
+			for _, v := range tt.values {
+				want[v] = true
+			}
+			if !reflect.DeepEqual(seen, want) {
+				t.Errorf("spawned values %v, want %v", seen, want)
+			}
+			s, tile, err := New(2, 2).WithRule(tt.rule).Spawn(r)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if !want[tile.Value] || s.At(tile.Pos.X, tile.Pos.Y) != tile.Value {
+				t.Errorf("Spawn placed %+v", tile)
+			}
+		})
+	}
+}
+
+func TestRuleByName(t *testing.T) {
+	for _, r := range Rules {
+		got, err := RuleByName(r.Name())
+		if err != nil || got != r {
+			t.Errorf("RuleByName(%q) = %v, %v", r.Name(), got, err)
+		}
+	}
+	if got, err := RuleByName(""); err != nil || got != Classic {
+		t.Errorf("RuleByName(\"\") = %v, %v, want classic", got, err)
+	}
+	if _, err := RuleByName("nope"); err == nil {
+		t.Errorf("RuleByName(\"nope\") succeeded")
+	}
+}
+
+func TestRuleRank(t *testing.T) {
+	tests := []struct {
+		rule  MergeRule
+		value int
+		want  int
+	}{
+		{Classic, 2, 1},
+		{Classic, 2048, 11},
+		{Fibonacci, 1, 1},
+		{Fibonacci, 8, 5},
+		{PowersOfThree, 27, 3},
+		{Threes, 3, 3},
+		{Threes, 12, 5},
+		{Threes, 5, 0},
+	}
+	for _, tt := range tests {
+		if got := tt.rule.Rank(tt.value); got != tt.want {
+			t.Errorf("%s Rank(%d) = %d, want %d", tt.rule.Name(), tt.value, got, tt.want)
+		}
+	}
+}
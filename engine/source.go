@@ -0,0 +1,45 @@
+package engine
+
+// Source 可以保存和恢复状态的随机数 (splitmix64)
+// 保存State()之后用SetState恢复，可以得到完全相同的随机序列
+type Source struct {
+	seed  uint64 //初始化时的种子
+	state uint64
+}
+
+// NewSource 用seed初始化随机数
+func NewSource(seed uint64) *Source {
+	return &Source{seed: seed, state: seed}
+}
+
+// Seed 初始化时的种子
+func (s *Source) Seed() uint64 {
+	return s.seed
+}
+
+// Uint64 返回下一个随机数
+func (s *Source) Uint64() uint64 {
+	s.state += 0x9e3779b97f4a7c15
+	z := s.state
+	z = (z ^ (z >> 30)) * 0xbf58476d1ce4e5b9
+	z = (z ^ (z >> 27)) * 0x94d049bb133111eb
+	return z ^ (z >> 31)
+}
+
+// Intn 返回[0,n)之间的随机数
+func (s *Source) Intn(n int) int {
+	if n <= 0 {
+		panic("not reach")
+	}
+	return int(s.Uint64() % uint64(n))
+}
+
+// State 当前的内部状态
+func (s *Source) State() uint64 {
+	return s.state
+}
+
+// SetState 恢复到之前保存的内部状态
+func (s *Source) SetState(state uint64) {
+	s.state = state
+}
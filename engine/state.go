@@ -0,0 +1,344 @@
+// Package engine 2048的规则引擎，不依赖ebiten，可以在没有窗口的情况下运行游戏
+package engine
+
+import (
+	"errors"
+)
+
+// Wall 墙的值 格子不能进入或穿过墙
+const Wall = -1
+
+// ErrNoSpace 棋盘上没有空位
+var ErrNoSpace = errors.New("twenty48: there is no space to add a new tile")
+
+// Rand 生成随机格子需要的随机数
+type Rand interface {
+	// Intn 返回[0,n)之间的随机数
+	Intn(n int) int
+}
+
+// Pos 棋盘上的坐标
+type Pos struct {
+	X int //x轴
+	Y int //y轴
+}
+
+// Tile 棋盘上有值的格子
+type Tile struct {
+	Pos
+	Value int //格子的数字
+}
+
+// TileMove 一次移动中单个格子的变化
+type TileMove struct {
+	From  Pos //移动前的位置
+	To    Pos //移动后的位置
+	Value int //移动前的值
+	Next  int //移动后的值 合并中被吃掉的格子为0
+}
+
+// Merge 一次合并
+type Merge struct {
+	Pos          //合并后的位置
+	Values []int //参与合并的值
+	Value  int   //合并后的值
+}
+
+// MoveResult 一次移动的结果
+type MoveResult struct {
+	Moved  bool       //是否有格子移动
+	Moves  []TileMove //发生变化的格子
+	Merges []Merge    //这次移动中的合并
+	Score  int        //这次移动得到的分数
+}
+
+// State 棋盘状态
+// State是值类型，所有修改都返回新的State，不会影响原来的值
+type State struct {
+	width  int       //棋盘的宽 列数
+	height int       //棋盘的高 行数
+	cells  []int     //每个位置的值 0为空 Wall为墙
+	score  int       //累计的分数 合并出的值之和
+	rule   MergeRule //合并规则 nil为Classic
+}
+
+//  0  1  2  3  4
+//  5  6  7  8  9
+// 10 11 12 13 14
+
+// New 初始化一个width列height行的空棋盘
+func New(width, height int) State {
+	return State{
+		width:  width,
+		height: height,
+		cells:  make([]int, width*height),
+	}
+}
+
+// WithRule 返回使用rule合并的新棋盘
+func (s State) WithRule(rule MergeRule) State {
+	s.rule = rule
+	return s
+}
+
+// Rule 棋盘的合并规则
+func (s State) Rule() MergeRule {
+	if s.rule == nil {
+		return Classic
+	}
+	return s.rule
+}
+
+// Size 棋盘的宽和高
+func (s State) Size() (int, int) {
+	return s.width, s.height
+}
+
+// index 计算位置在cells中的下标
+func (s State) index(x, y int) int {
+	return x + y*s.width
+}
+
+// pos 计算cells中下标对应的位置
+func (s State) pos(i int) Pos {
+	return Pos{X: i % s.width, Y: i / s.width}
+}
+
+// contains 位置是否在棋盘内
+func (s State) contains(x, y int) bool {
+	return 0 <= x && x < s.width && 0 <= y && y < s.height
+}
+
+// clone 复制一份棋盘
+func (s State) clone() State {
+	n := s
+	n.cells = append([]int(nil), s.cells...)
+	return n
+}
+
+// At 该位置的值 空格子为0 墙为Wall
+func (s State) At(x, y int) int {
+	if !s.contains(x, y) {
+		return 0
+	}
+	return s.cells[s.index(x, y)]
+}
+
+// Set 返回该位置被设置为v的新棋盘
+func (s State) Set(x, y, v int) State {
+	if !s.contains(x, y) {
+		panic("not reach")
+	}
+	n := s.clone()
+	n.cells[n.index(x, y)] = v
+	return n
+}
+
+// IsWall 该位置是否是墙
+func (s State) IsWall(x, y int) bool {
+	return s.contains(x, y) && s.cells[s.index(x, y)] == Wall
+}
+
+// Walls 棋盘上所有的墙
+func (s State) Walls() []Pos {
+	var walls []Pos
+	for i, v := range s.cells {
+		if v == Wall {
+			walls = append(walls, s.pos(i))
+		}
+	}
+	return walls
+}
+
+// Layout 只保留墙和合并规则的空棋盘
+func (s State) Layout() State {
+	n := New(s.width, s.height).WithRule(s.rule)
+	for i, v := range s.cells {
+		if v == Wall {
+			n.cells[i] = Wall
+		}
+	}
+	return n
+}
+
+// Tiles 棋盘上所有有值的格子 不包括墙
+func (s State) Tiles() []Tile {
+	var tiles []Tile
+	for i, v := range s.cells {
+		if v <= 0 {
+			continue
+		}
+		tiles = append(tiles, Tile{Pos: s.pos(i), Value: v})
+	}
+	return tiles
+}
+
+// EmptyCells 棋盘上所有的空位置
+func (s State) EmptyCells() []Pos {
+	var cells []Pos
+	for i, v := range s.cells {
+		if v != 0 {
+			continue
+		}
+		cells = append(cells, s.pos(i))
+	}
+	return cells
+}
+
+// Score 累计的分数
+func (s State) Score() int {
+	return s.score
+}
+
+// MaxTile 棋盘上最大的值
+func (s State) MaxTile() int {
+	m := 0
+	for _, v := range s.cells {
+		if m < v {
+			m = v
+		}
+	}
+	return m
+}
+
+// CanMove 是否还有可以移动的方向
+// 棋盘满了但还能合并时也返回true
+func (s State) CanMove() bool {
+	for _, d := range Dirs {
+		if _, r := s.Move(d); r.Moved {
+			return true
+		}
+	}
+	return false
+}
+
+// Reached 是否已经合并出target
+func (s State) Reached(target int) bool {
+	return target <= s.MaxTile()
+}
+
+// Spawn 在随机的空位置增加一个格子
+func (s State) Spawn(r Rand) (State, Tile, error) {
+	//初始化一个没有棋子的空格子集
+	availableCells := s.EmptyCells()
+	//判断是否还有空格子
+	if len(availableCells) == 0 {
+		return s, Tile{}, ErrNoSpace
+	}
+	//随机取出一个位置
+	c := availableCells[r.Intn(len(availableCells))]
+	//按合并规则的权重决定格子的值
+	v := spawnValue(s.Rule(), r)
+	t := Tile{Pos: c, Value: v}
+	return s.Set(c.X, c.Y, v), t, nil
+}
+
+// lines 按移动方向把棋盘分成多行
+// 每一行从移动的目的地一侧开始排列
+func (s State) lines(dir Dir) [][]Pos {
+	vx, vy := dir.Vector()
+	//左右移动时每一行是一条线，上下移动时每一列是一条线
+	n, length := s.height, s.width
+	if vx == 0 {
+		n, length = s.width, s.height
+	}
+	lines := make([][]Pos, n)
+	for i := 0; i < n; i++ {
+		line := make([]Pos, length)
+		for j := 0; j < length; j++ {
+			//靠近目的地的一侧排在前面
+			k := j
+			if 0 < vx || 0 < vy {
+				k = length - 1 - j
+			}
+			if vx != 0 {
+				line[j] = Pos{X: k, Y: i}
+			} else {
+				line[j] = Pos{X: i, Y: k}
+			}
+		}
+		lines[i] = line
+	}
+	return lines
+}
+
+// Move 向dir方向移动，返回移动后的棋盘和移动的结果
+// 没有格子可以移动时返回原棋盘
+func (s State) Move(dir Dir) (State, MoveResult) {
+	next := s.clone()
+	var result MoveResult
+	for _, line := range s.lines(dir) {
+		next.moveLine(s, line, &result)
+	}
+	if len(result.Moves) == 0 {
+		return s, result
+	}
+	result.Moved = true
+	next.score += result.Score
+	return next, result
+}
+
+// moveLine 移动一行格子，line从目的地一侧开始
+// 墙把一行分成几段，每段单独移动
+func (s State) moveLine(prev State, line []Pos, result *MoveResult) {
+	start := 0
+	for i, p := range line {
+		if prev.cells[prev.index(p.X, p.Y)] != Wall {
+			continue
+		}
+		s.moveSegment(prev, line[start:i], result)
+		start = i + 1
+	}
+	s.moveSegment(prev, line[start:], result)
+}
+
+// moveSegment 移动两堵墙之间的一段格子，line从目的地一侧开始
+// 从离目的地最近的格子开始，能按规则合并的GroupSize个格子合并成一个
+// 从prev中读取移动前的值写入s，并把发生变化的格子和合并记录到result
+func (s State) moveSegment(prev State, line []Pos, result *MoveResult) {
+	rule := s.Rule()
+	k := rule.GroupSize()
+	//这一段中的格子 离目的地近的在前
+	var tiles []Tile
+	for _, p := range line {
+		s.cells[s.index(p.X, p.Y)] = 0
+		if v := prev.cells[prev.index(p.X, p.Y)]; v != 0 {
+			tiles = append(tiles, Tile{Pos: p, Value: v})
+		}
+	}
+	//已经放置的格子个数
+	placed := 0
+	for i := 0; i < len(tiles); {
+		to := line[placed]
+		placed++
+		//接下来的k个格子可以合并
+		if i+k <= len(tiles) {
+			group := tiles[i : i+k]
+			values := make([]int, k)
+			for j, t := range group {
+				values[j] = t.Value
+			}
+			if v, ok := rule.Merge(values); ok {
+				s.cells[s.index(to.X, to.Y)] = v
+				//最后一个格子变成合并后的值，其余的被吃掉变为0
+				for j, t := range group {
+					next := 0
+					if j == k-1 {
+						next = v
+					}
+					result.Moves = append(result.Moves, TileMove{From: t.Pos, To: to, Value: t.Value, Next: next})
+				}
+				result.Merges = append(result.Merges, Merge{Pos: to, Values: values, Value: v})
+				result.Score += v
+				i += k
+				continue
+			}
+		}
+		t := tiles[i]
+		s.cells[s.index(to.X, to.Y)] = t.Value
+		if to != t.Pos {
+			result.Moves = append(result.Moves, TileMove{From: t.Pos, To: to, Value: t.Value, Next: t.Value})
+		}
+		i++
+	}
+}
@@ -0,0 +1,250 @@
+package engine
+
+import (
+	"reflect"
+	"testing"
+)
+
+// grid 按行列出的棋盘 0为空位置 Wall为墙
+func grid(rows ...[]int) State {
+	s := New(len(rows[0]), len(rows))
+	for y, row := range rows {
+		copy(s.cells[y*s.width:], row)
+	}
+	return s
+}
+
+// rowsOf 按行列出棋盘的值
+func rowsOf(s State) [][]int {
+	rows := make([][]int, s.height)
+	for y := range rows {
+		rows[y] = append([]int(nil), s.cells[y*s.width:(y+1)*s.width]...)
+	}
+	return rows
+}
+
+func TestMove(t *testing.T) {
+	tests := []struct {
+		name  string
+		start [][]int
+		dir   Dir
+		want  [][]int
+		score int
+	}{
+		{
+			name:  "slide left",
+			start: [][]int{{0, 0, 2, 0}, {0, 4, 0, 8}},
+			dir:   DirLeft,
+			want:  [][]int{{2, 0, 0, 0}, {4, 8, 0, 0}},
+		},
+		{
+			name:  "slide right",
+			start: [][]int{{2, 0, 0, 0}, {4, 0, 8, 0}},
+			dir:   DirRight,
+			want:  [][]int{{0, 0, 0, 2}, {0, 0, 4, 8}},
+		},
+		{
+			name:  "merge once per tile",
+			start: [][]int{{2, 2, 2, 2}, {4, 4, 8, 0}},
+			dir:   DirLeft,
+			want:  [][]int{{4, 4, 0, 0}, {8, 8, 0, 0}},
+			score: 4 + 4 + 8,
+		},
+		{
+			name:  "merge nearest the destination first",
+			start: [][]int{{2, 2, 2, 0}},
+			dir:   DirRight,
+			want:  [][]int{{0, 0, 2, 4}},
+			score: 4,
+		},
+		{
+			name:  "merge across gaps",
+			start: [][]int{{2, 0, 0, 2}},
+			dir:   DirLeft,
+			want:  [][]int{{4, 0, 0, 0}},
+			score: 4,
+		},
+		{
+			name:  "no chained merge",
+			start: [][]int{{4, 2, 2, 0}},
+			dir:   DirLeft,
+			want:  [][]int{{4, 4, 0, 0}},
+			score: 4,
+		},
+		{
+			name:  "slide up",
+			start: [][]int{{0, 2}, {2, 0}, {2, 4}},
+			dir:   DirUp,
+			want:  [][]int{{4, 2}, {0, 4}, {0, 0}},
+			score: 4,
+		},
+		{
+			name:  "slide down",
+			start: [][]int{{2, 2}, {0, 0}, {2, 4}},
+			dir:   DirDown,
+			want:  [][]int{{0, 0}, {0, 2}, {4, 4}},
+			score: 4,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			s := grid(tt.start...)
+			next, result := s.Move(tt.dir)
+			if !result.Moved {
+				t.Fatalf("Moved = false")
+			}
+			if got := rowsOf(next); !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("board = %v, want %v", got, tt.want)
+			}
+			if result.Score != tt.score || next.Score() != tt.score {
+				t.Errorf("score = %d (state %d), want %d", result.Score, next.Score(), tt.score)
+			}
+			//原来的棋盘不变
+			if got := rowsOf(s); !reflect.DeepEqual(got, tt.start) {
+				t.Errorf("original board changed to %v", got)
+			}
+		})
+	}
+}
+
+func TestMoveResult(t *testing.T) {
+	s := grid([]int{2, 2, 0, 4})
+	_, result := s.Move(DirLeft)
+	wantMoves := []TileMove{
+		{From: Pos{0, 0}, To: Pos{0, 0}, Value: 2, Next: 0},
+		{From: Pos{1, 0}, To: Pos{0, 0}, Value: 2, Next: 4},
+		{From: Pos{3, 0}, To: Pos{1, 0}, Value: 4, Next: 4},
+	}
+	if !reflect.DeepEqual(result.Moves, wantMoves) {
+		t.Errorf("moves = %+v, want %+v", result.Moves, wantMoves)
+	}
+	wantMerges := []Merge{{Pos: Pos{0, 0}, Values: []int{2, 2}, Value: 4}}
+	if !reflect.DeepEqual(result.Merges, wantMerges) {
+		t.Errorf("merges = %+v, want %+v", result.Merges, wantMerges)
+	}
+}
+
+func TestMoveNoOp(t *testing.T) {
+	s := grid(
+		[]int{2, 4, 0},
+		[]int{8, 0, 0},
+	)
+	next, result := s.Move(DirLeft)
+	if result.Moved || len(result.Moves) != 0 || len(result.Merges) != 0 || result.Score != 0 {
+		t.Errorf("result = %+v, want no move", result)
+	}
+	if !reflect.DeepEqual(rowsOf(next), rowsOf(s)) {
+		t.Errorf("board = %v, want unchanged", rowsOf(next))
+	}
+	if _, result := s.Move(DirUp); result.Moved {
+		t.Errorf("up moved a board packed to the top")
+	}
+	if _, result := s.Move(DirRight); !result.Moved {
+		t.Errorf("right did not move")
+	}
+}
+
+func TestCanMove(t *testing.T) {
+	tests := []struct {
+		name  string
+		state State
+		want  bool
+	}{
+		{"empty", New(4, 4), false},
+		{"has space", grid([]int{2, 0}, []int{4, 8}), true},
+		{"full with merge", grid([]int{2, 4}, []int{2, 8}), true},
+		{"full without merge", grid([]int{2, 4}, []int{4, 2}), false},
+		{"walls only", grid([]int{Wall, 2}, []int{4, Wall}), false},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := tt.state.CanMove(); got != tt.want {
+				t.Errorf("CanMove = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestStateAccessors(t *testing.T) {
+	s := grid(
+		[]int{2, 0, Wall},
+		[]int{0, 16, 4},
+	)
+	if got := s.MaxTile(); got != 16 {
+		t.Errorf("MaxTile = %d, want 16", got)
+	}
+	if !s.Reached(16) || s.Reached(32) {
+		t.Errorf("Reached is wrong for max tile 16")
+	}
+	if got := len(s.Tiles()); got != 3 {
+		t.Errorf("Tiles has %d tiles, want 3", got)
+	}
+	wantEmpty := []Pos{{1, 0}, {0, 1}}
+	if got := s.EmptyCells(); !reflect.DeepEqual(got, wantEmpty) {
+		t.Errorf("EmptyCells = %v, want %v", got, wantEmpty)
+	}
+	if got := s.Walls(); !reflect.DeepEqual(got, []Pos{{2, 0}}) {
+		t.Errorf("Walls = %v", got)
+	}
+	if s.At(-1, 0) != 0 || s.At(3, 0) != 0 {
+		t.Errorf("At outside the board is not 0")
+	}
+}
+
+func TestMoveNonSquare(t *testing.T) {
+	//5列3行 上下移动时每条线有3格，左右移动时有5格
+	start := [][]int{
+		{2, 0, 0, 4, 2},
+		{0, 0, 2, 4, 0},
+		{2, 8, 0, 0, 2},
+	}
+	tests := []struct {
+		dir  Dir
+		want [][]int
+	}{
+		{DirUp, [][]int{
+			{4, 8, 2, 8, 4},
+			{0, 0, 0, 0, 0},
+			{0, 0, 0, 0, 0},
+		}},
+		{DirDown, [][]int{
+			{0, 0, 0, 0, 0},
+			{0, 0, 0, 0, 0},
+			{4, 8, 2, 8, 4},
+		}},
+		{DirLeft, [][]int{
+			{2, 4, 2, 0, 0},
+			{2, 4, 0, 0, 0},
+			{2, 8, 2, 0, 0},
+		}},
+		{DirRight, [][]int{
+			{0, 0, 2, 4, 2},
+			{0, 0, 0, 2, 4},
+			{0, 0, 2, 8, 2},
+		}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.dir.String(), func(t *testing.T) {
+			next, result := grid(start...).Move(tt.dir)
+			if !result.Moved {
+				t.Fatal("Moved = false")
+			}
+			if w, h := next.Size(); w != 5 || h != 3 {
+				t.Fatalf("size = %dx%d, want 5x3", w, h)
+			}
+			if got := rowsOf(next); !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("board = %v, want %v", got, tt.want)
+			}
+		})
+	}
+
+	//竖长的棋盘
+	tall := grid([]int{2}, []int{0}, []int{2}, []int{0}, []int{4})
+	next, _ := tall.Move(DirDown)
+	if got, want := rowsOf(next), [][]int{{0}, {0}, {0}, {4}, {4}}; !reflect.DeepEqual(got, want) {
+		t.Errorf("1x5 down = %v, want %v", got, want)
+	}
+	if _, result := tall.Move(DirLeft); result.Moved {
+		t.Errorf("1x5 left moved")
+	}
+}
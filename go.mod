@@ -3,8 +3,10 @@ module gameTest
 go 1.20
 
 require (
+	github.com/BurntSushi/toml v1.3.2
 	github.com/hajimehoshi/ebiten/v2 v2.5.6
 	golang.org/x/image v0.6.0
+	golang.org/x/term v0.10.0
 )
 
 require (
@@ -15,7 +17,7 @@ require (
 	golang.org/x/mobile v0.0.0-20230531173138-3c911d8e3eda // indirect
 	golang.org/x/mod v0.9.0 // indirect
 	golang.org/x/sync v0.1.0 // indirect
-	golang.org/x/sys v0.7.0 // indirect
+	golang.org/x/sys v0.10.0 // indirect
 	golang.org/x/text v0.8.0 // indirect
 	golang.org/x/tools v0.7.0 // indirect
 )
@@ -1,9 +1,13 @@
 package main
 
 import (
+	"flag"
+	"gameTest/ai"
 	"gameTest/core"
+	"gameTest/engine"
 	"github.com/hajimehoshi/ebiten/v2"
 	"log"
+	"os"
 )
 
 const (
@@ -11,13 +15,88 @@ const (
 	ScreenHeight = 600 //初始画布高
 )
 
+var (
+	boardSize = flag.Int("size", core.DefaultBoardSize, "正方形棋盘的大小")
+	width     = flag.Int("width", 0, "棋盘的列数 0为和size一样")
+	height    = flag.Int("height", 0, "棋盘的行数 0为和size一样")
+	winTarget = flag.Int("target", 0, "合并出这个值就算赢 0为合并规则的目标")
+	rule      = flag.String("rule", "classic", "合并规则 classic, fibonacci, pow3, threes")
+	undoLimit = flag.Int("undos", 0, "每局最多撤销几次 0为不限制")
+	seed      = flag.Uint64("seed", 0, "每局的随机种子 0为每局使用新的种子")
+	replay    = flag.String("replay", "", "回放录像文件")
+	autoplay  = flag.Bool("autoplay", false, "开始时由AI自动玩 游戏中按A切换")
+	aiDepth   = flag.Int("ai-depth", ai.DefaultDepth, "AI的搜索深度")
+	aiBudget  = flag.Duration("ai-budget", ai.DefaultBudget, "AI每一步的时间预算")
+	level     = flag.String("level", "", "棋盘布局文件 '.'为空位置 '#'为墙")
+	themeName = flag.String("theme", "", "主题的名字或者主题文件 空为设置中保存的主题")
+	moveQueue = flag.Int("queue", core.DefaultMoveQueue, "动画时最多缓存几步移动 0为不缓存")
+	fastFwd   = flag.Bool("fast-forward", false, "动画时收到新的移动立即走完动画")
+	skipTitle = flag.Bool("skip-title", false, "不显示标题画面，直接开始游戏")
+)
+
 func main() {
-	g, err := core.NewGame(ScreenWidth, ScreenHeight)
+	flag.Parse()
+	options := core.Options{
+		Width:       *boardSize,
+		Height:      *boardSize,
+		WinTarget:   *winTarget,
+		UndoLimit:   *undoLimit,
+		Seed:        *seed,
+		Autoplay:    *autoplay,
+		Theme:       *themeName,
+		MoveQueue:   *moveQueue,
+		FastForward: *fastFwd,
+		SkipTitle:   *skipTitle,
+		AI: ai.Options{
+			Depth:  *aiDepth,
+			Budget: *aiBudget,
+		},
+	}
+	r, err := engine.RuleByName(*rule)
+	if err != nil {
+		log.Fatal(err)
+	}
+	options.Rule = r
+	if *width != 0 {
+		options.Width = *width
+	}
+	if *height != 0 {
+		options.Height = *height
+	}
+	if *level != "" {
+		data, err := os.ReadFile(*level)
+		if err != nil {
+			log.Fatal(err)
+		}
+		layout, err := engine.ParseLayout(string(data))
+		if err != nil {
+			log.Fatal(err)
+		}
+		options.Width, options.Height = layout.Size()
+		options.Walls = layout.Walls()
+	}
+	//命令行指定了这局的棋盘时不继续存档，否则存档的棋盘会覆盖这些选项
+	flag.Visit(func(f *flag.Flag) {
+		switch f.Name {
+		case "size", "width", "height", "rule", "level", "seed":
+			options.NoResume = true
+		}
+	})
+	if *replay != "" {
+		r, err := engine.ReadReplayFile(*replay)
+		if err != nil {
+			log.Fatal(err)
+		}
+		options.Replay = r
+	}
+	g, err := core.NewGame(ScreenWidth, ScreenHeight, options)
 	if err != nil {
 		log.Fatal(err)
 	}
 	ebiten.SetWindowSize(g.ScreenWidth, g.ScreenHeight)
 	ebiten.SetWindowTitle("GameDemo")
+	//关闭窗口时由游戏保存后退出
+	ebiten.SetWindowClosingHandled(true)
 	if err := ebiten.RunGame(g); err != nil {
 		log.Fatal(err)
 	}
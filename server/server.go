@@ -0,0 +1,365 @@
+// Package server 用HTTP JSON API玩2048 不依赖ebiten
+//
+//	POST   /games            开始新的一局 请求体可以为空或者newGameRequest
+//	GET    /games/{id}        这局的棋盘、分数和是否结束
+//	POST   /games/{id}/moves  移动一步 请求体为 {"dir": "Up"} 方向为Up、Right、Down、Left
+//	GET    /games/{id}/replay 这局的录像 和窗口版保存的录像格式一样
+//	DELETE /games/{id}        删除这局
+package server
+
+import (
+	"crypto/rand"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"gameTest/engine"
+	"io"
+	"net/http"
+	"strings"
+	"sync"
+	"time"
+)
+
+const (
+	MinBoardSize = 2 //棋盘最少的行数和列数
+	MaxBoardSize = 8 //棋盘最多的行数和列数
+
+	defaultBoardSize = 4       //没有指定时棋盘的大小
+	maxBodySize      = 1 << 16 //请求体的最大字节数
+)
+
+var (
+	errNotFound = errors.New("twenty48: game not found")
+	errTooMany  = errors.New("twenty48: too many games")
+)
+
+// Server 保存所有进行中的游戏 可以同时处理多个请求
+type Server struct {
+	MaxGames int //最多同时保存几局 0为不限制
+
+	mu    sync.Mutex
+	games map[string]*session
+}
+
+// New 初始化没有游戏的Server
+func New() *Server {
+	return &Server{games: map[string]*session{}}
+}
+
+// session 一局游戏 每局有自己的锁，不同的局可以同时移动
+type session struct {
+	mu      sync.Mutex
+	id      string
+	state   engine.State
+	rng     *engine.Source
+	target  int
+	record  *engine.Replay
+	started time.Time
+	updated time.Time //最后一次移动的时间
+}
+
+// newGameRequest 开始新的一局的参数 没有设置的项使用默认值
+type newGameRequest struct {
+	Width  int          `json:"width"`  //0为4
+	Height int          `json:"height"` //0为4
+	Walls  []engine.Pos `json:"walls"`
+	Rule   string       `json:"rule"`   //空为classic
+	Seed   uint64       `json:"seed"`   //0为随机的种子
+	Target int          `json:"target"` //0为合并规则的目标
+}
+
+// moveRequest 移动的参数
+type moveRequest struct {
+	Dir *engine.Dir `json:"dir"`
+}
+
+// gameResponse 一局游戏的状态
+type gameResponse struct {
+	ID      string       `json:"id"`
+	Board   engine.State `json:"board"`
+	Seed    uint64       `json:"seed"`
+	Moves   int          `json:"moves"`
+	Score   int          `json:"score"`
+	MaxTile int          `json:"maxTile"`
+	Target  int          `json:"target"`
+	Won     bool         `json:"won"`
+	Over    bool         `json:"over"`
+}
+
+// moveResponse 移动的结果
+type moveResponse struct {
+	Moved  bool         `json:"moved"`
+	Gained int          `json:"gained"` //这一步得到的分数
+	Game   gameResponse `json:"game"`
+}
+
+// errorResponse 出错时返回的内容
+type errorResponse struct {
+	Error string `json:"error"`
+}
+
+// ServeHTTP 按路径分发请求
+func (s *Server) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	parts := strings.Split(strings.Trim(r.URL.Path, "/"), "/")
+	if parts[0] != "games" {
+		writeError(w, http.StatusNotFound, errNotFound)
+		return
+	}
+	switch {
+	case len(parts) == 1 && r.Method == http.MethodPost:
+		s.handleNew(w, r)
+	case len(parts) == 2 && r.Method == http.MethodGet:
+		s.handleGet(w, parts[1])
+	case len(parts) == 2 && r.Method == http.MethodDelete:
+		s.handleDelete(w, parts[1])
+	case len(parts) == 3 && parts[2] == "moves" && r.Method == http.MethodPost:
+		s.handleMove(w, r, parts[1])
+	case len(parts) == 3 && parts[2] == "replay" && r.Method == http.MethodGet:
+		s.handleReplay(w, parts[1])
+	case len(parts) <= 2 || len(parts) == 3 && (parts[2] == "moves" || parts[2] == "replay"):
+		//路径存在但是不支持这个方法
+		writeError(w, http.StatusMethodNotAllowed, fmt.Errorf("twenty48: method %s not allowed", r.Method))
+	default:
+		writeError(w, http.StatusNotFound, errNotFound)
+	}
+}
+
+// handleNew 开始新的一局
+func (s *Server) handleNew(w http.ResponseWriter, r *http.Request) {
+	var req newGameRequest
+	if err := readJSON(r, &req); err != nil {
+		writeError(w, http.StatusBadRequest, err)
+		return
+	}
+	g, err := newSession(req)
+	if err != nil {
+		writeError(w, http.StatusBadRequest, err)
+		return
+	}
+	if err := s.add(g); err != nil {
+		writeError(w, http.StatusServiceUnavailable, err)
+		return
+	}
+	g.mu.Lock()
+	defer g.mu.Unlock()
+	writeJSON(w, http.StatusCreated, g.response())
+}
+
+// handleGet 返回这局的状态
+func (s *Server) handleGet(w http.ResponseWriter, id string) {
+	g, ok := s.get(id)
+	if !ok {
+		writeError(w, http.StatusNotFound, errNotFound)
+		return
+	}
+	g.mu.Lock()
+	defer g.mu.Unlock()
+	writeJSON(w, http.StatusOK, g.response())
+}
+
+// handleDelete 删除这局
+func (s *Server) handleDelete(w http.ResponseWriter, id string) {
+	s.mu.Lock()
+	_, ok := s.games[id]
+	delete(s.games, id)
+	s.mu.Unlock()
+	if !ok {
+		writeError(w, http.StatusNotFound, errNotFound)
+		return
+	}
+	w.WriteHeader(http.StatusNoContent)
+}
+
+// handleMove 移动一步 不能移动的方向返回moved为false
+func (s *Server) handleMove(w http.ResponseWriter, r *http.Request, id string) {
+	g, ok := s.get(id)
+	if !ok {
+		writeError(w, http.StatusNotFound, errNotFound)
+		return
+	}
+	var req moveRequest
+	if err := readJSON(r, &req); err != nil {
+		writeError(w, http.StatusBadRequest, err)
+		return
+	}
+	if req.Dir == nil {
+		writeError(w, http.StatusBadRequest, errors.New("twenty48: missing dir"))
+		return
+	}
+	dir := *req.Dir
+	g.mu.Lock()
+	defer g.mu.Unlock()
+	next, result, moved := engine.Step(g.state, dir, g.rng)
+	if moved {
+		g.state = next
+		g.updated = time.Now()
+		g.record.Record(dir, g.updated.Sub(g.started))
+	}
+	writeJSON(w, http.StatusOK, moveResponse{
+		Moved:  moved,
+		Gained: result.Score,
+		Game:   g.response(),
+	})
+}
+
+// handleReplay 返回这局的录像
+func (s *Server) handleReplay(w http.ResponseWriter, id string) {
+	g, ok := s.get(id)
+	if !ok {
+		writeError(w, http.StatusNotFound, errNotFound)
+		return
+	}
+	g.mu.Lock()
+	defer g.mu.Unlock()
+	writeJSON(w, http.StatusOK, g.record)
+}
+
+// add 保存新的一局
+func (s *Server) add(g *session) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if 0 < s.MaxGames && s.MaxGames <= len(s.games) {
+		return errTooMany
+	}
+	s.games[g.id] = g
+	return nil
+}
+
+// get 根据id找到一局
+func (s *Server) get(id string) (*session, bool) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	g, ok := s.games[id]
+	return g, ok
+}
+
+// Expire 删除超过maxIdle没有移动的局 返回删除了几局
+func (s *Server) Expire(maxIdle time.Duration) int {
+	//先复制一份 检查每局时不占用s.mu，不会挡住其他局的请求
+	s.mu.Lock()
+	games := make(map[string]*session, len(s.games))
+	for id, g := range s.games {
+		games[id] = g
+	}
+	s.mu.Unlock()
+
+	now := time.Now()
+	var idle []string
+	for id, g := range games {
+		g.mu.Lock()
+		if maxIdle < now.Sub(g.updated) {
+			idle = append(idle, id)
+		}
+		g.mu.Unlock()
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	n := 0
+	for _, id := range idle {
+		//检查之后可能已经被删除或者换成了新的一局
+		if s.games[id] == games[id] {
+			delete(s.games, id)
+			n++
+		}
+	}
+	return n
+}
+
+// newSession 按请求开始新的一局
+func newSession(req newGameRequest) (*session, error) {
+	if req.Width == 0 {
+		req.Width = defaultBoardSize
+	}
+	if req.Height == 0 {
+		req.Height = defaultBoardSize
+	}
+	if req.Width < MinBoardSize || MaxBoardSize < req.Width || req.Height < MinBoardSize || MaxBoardSize < req.Height {
+		return nil, fmt.Errorf("twenty48: board size %dx%d out of range", req.Width, req.Height)
+	}
+	rule, err := engine.RuleByName(req.Rule)
+	if err != nil {
+		return nil, err
+	}
+	layout := engine.New(req.Width, req.Height).WithRule(rule)
+	for _, p := range req.Walls {
+		if p.X < 0 || req.Width <= p.X || p.Y < 0 || req.Height <= p.Y {
+			return nil, fmt.Errorf("twenty48: wall (%d, %d) out of board", p.X, p.Y)
+		}
+		layout = layout.Set(p.X, p.Y, engine.Wall)
+	}
+	seed := req.Seed
+	if seed == 0 {
+		seed = uint64(time.Now().UnixNano())
+	}
+	id, err := newID()
+	if err != nil {
+		return nil, err
+	}
+	rng := engine.NewSource(seed)
+	state, err := engine.Start(layout, rng)
+	if err != nil {
+		return nil, err
+	}
+	target := req.Target
+	if target <= 0 {
+		target = rule.Target()
+	}
+	now := time.Now()
+	return &session{
+		id:      id,
+		state:   state,
+		rng:     rng,
+		target:  target,
+		record:  engine.NewReplay(layout, seed),
+		started: now,
+		updated: now,
+	}, nil
+}
+
+// newID 随机的16位十六进制id
+func newID() (string, error) {
+	var b [8]byte
+	if _, err := rand.Read(b[:]); err != nil {
+		return "", err
+	}
+	return hex.EncodeToString(b[:]), nil
+}
+
+// response 这局的状态 调用时要持有g.mu
+func (g *session) response() gameResponse {
+	return gameResponse{
+		ID:      g.id,
+		Board:   g.state,
+		Seed:    g.rng.Seed(),
+		Moves:   len(g.record.Moves),
+		Score:   g.state.Score(),
+		MaxTile: g.state.MaxTile(),
+		Target:  g.target,
+		Won:     g.state.Reached(g.target),
+		Over:    !g.state.CanMove(),
+	}
+}
+
+// readJSON 解码请求体 请求体为空时保持默认值
+func readJSON(r *http.Request, v interface{}) error {
+	dec := json.NewDecoder(http.MaxBytesReader(nil, r.Body, maxBodySize))
+	dec.DisallowUnknownFields()
+	if err := dec.Decode(v); err != nil && !errors.Is(err, io.EOF) {
+		return fmt.Errorf("twenty48: invalid request: %w", err)
+	}
+	return nil
+}
+
+// writeJSON 把v编码成json返回
+func writeJSON(w http.ResponseWriter, status int, v interface{}) {
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(status)
+	json.NewEncoder(w).Encode(v)
+}
+
+// writeError 返回错误
+func writeError(w http.ResponseWriter, status int, err error) {
+	writeJSON(w, status, errorResponse{Error: err.Error()})
+}
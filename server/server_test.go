@@ -0,0 +1,281 @@
+package server
+
+import (
+	"encoding/json"
+	"gameTest/engine"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+)
+
+// do 发送请求 返回状态码和响应体
+func do(t *testing.T, h http.Handler, method, path, body string) (int, []byte) {
+	t.Helper()
+	req := httptest.NewRequest(method, path, strings.NewReader(body))
+	rec := httptest.NewRecorder()
+	h.ServeHTTP(rec, req)
+	return rec.Code, rec.Body.Bytes()
+}
+
+// decode 解码响应体
+func decode(t *testing.T, data []byte, v interface{}) {
+	t.Helper()
+	if err := json.Unmarshal(data, v); err != nil {
+		t.Fatalf("decode %s: %v", data, err)
+	}
+}
+
+// create 开始新的一局 返回这局的状态
+func create(t *testing.T, h http.Handler, body string) gameResponse {
+	t.Helper()
+	code, data := do(t, h, http.MethodPost, "/games", body)
+	if code != http.StatusCreated {
+		t.Fatalf("create: status %d %s", code, data)
+	}
+	var g gameResponse
+	decode(t, data, &g)
+	return g
+}
+
+func TestGameLifecycle(t *testing.T) {
+	s := New()
+	g := create(t, s, `{"width": 4, "height": 3, "seed": 42}`)
+	if g.ID == "" || g.Seed != 42 || g.Moves != 0 || g.Over {
+		t.Fatalf("new game = %+v", g)
+	}
+	if w, h := g.Board.Size(); w != 4 || h != 3 {
+		t.Fatalf("board size = %dx%d, want 4x3", w, h)
+	}
+
+	code, data := do(t, s, http.MethodGet, "/games/"+g.ID, "")
+	if code != http.StatusOK {
+		t.Fatalf("get: status %d %s", code, data)
+	}
+	var got gameResponse
+	decode(t, data, &got)
+	if got.ID != g.ID || got.Score != g.Score {
+		t.Errorf("get = %+v, want %+v", got, g)
+	}
+
+	//走到有一步能移动为止
+	moved := 0
+	var last gameResponse
+	for _, d := range []string{"Up", "Left", "Down", "Right", "Up", "Left"} {
+		code, data := do(t, s, http.MethodPost, "/games/"+g.ID+"/moves", `{"dir": "`+d+`"}`)
+		if code != http.StatusOK {
+			t.Fatalf("move %s: status %d %s", d, code, data)
+		}
+		var m moveResponse
+		decode(t, data, &m)
+		if m.Moved {
+			moved++
+		}
+		if m.Game.Moves != moved {
+			t.Errorf("moves = %d, want %d", m.Game.Moves, moved)
+		}
+		last = m.Game
+	}
+	if moved == 0 {
+		t.Fatal("no move was made")
+	}
+
+	//用录像重新走一遍得到同样的棋盘
+	code, data = do(t, s, http.MethodGet, "/games/"+g.ID+"/replay", "")
+	if code != http.StatusOK {
+		t.Fatalf("replay: status %d %s", code, data)
+	}
+	r, err := engine.ReadReplay(strings.NewReader(string(data)))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(r.Moves) != moved || r.Seed != 42 {
+		t.Errorf("replay has %d moves with seed %d, want %d with seed 42", len(r.Moves), r.Seed, moved)
+	}
+	state, err := engine.Play(r.Layout(), r.Seed, r.Dirs())
+	if err != nil {
+		t.Fatal(err)
+	}
+	want, _ := json.Marshal(last.Board)
+	replayed, _ := json.Marshal(state)
+	if string(want) != string(replayed) {
+		t.Errorf("replayed board %s, want %s", replayed, want)
+	}
+
+	if code, data := do(t, s, http.MethodDelete, "/games/"+g.ID, ""); code != http.StatusNoContent {
+		t.Fatalf("delete: status %d %s", code, data)
+	}
+	if code, _ := do(t, s, http.MethodGet, "/games/"+g.ID, ""); code != http.StatusNotFound {
+		t.Errorf("get after delete: status %d, want %d", code, http.StatusNotFound)
+	}
+}
+
+func TestDefaults(t *testing.T) {
+	s := New()
+	//请求体为空时使用默认值
+	g := create(t, s, "")
+	if w, h := g.Board.Size(); w != defaultBoardSize || h != defaultBoardSize {
+		t.Errorf("board size = %dx%d, want %dx%d", w, h, defaultBoardSize, defaultBoardSize)
+	}
+	if g.Target != engine.Classic.Target() {
+		t.Errorf("target = %d, want %d", g.Target, engine.Classic.Target())
+	}
+}
+
+func TestErrors(t *testing.T) {
+	s := New()
+	g := create(t, s, "")
+	tests := []struct {
+		name   string
+		method string
+		path   string
+		body   string
+		status int
+	}{
+		{"bad dir", http.MethodPost, "/games/" + g.ID + "/moves", `{"dir": "Diagonal"}`, http.StatusBadRequest},
+		{"missing dir", http.MethodPost, "/games/" + g.ID + "/moves", `{}`, http.StatusBadRequest},
+		{"missing body", http.MethodPost, "/games/" + g.ID + "/moves", "", http.StatusBadRequest},
+		{"invalid body", http.MethodPost, "/games/" + g.ID + "/moves", `{"dir":`, http.StatusBadRequest},
+		{"unknown field", http.MethodPost, "/games/" + g.ID + "/moves", `{"direction": "Up"}`, http.StatusBadRequest},
+		{"invalid new game", http.MethodPost, "/games", `[1, 2]`, http.StatusBadRequest},
+		{"board too big", http.MethodPost, "/games", `{"width": 9}`, http.StatusBadRequest},
+		{"unknown rule", http.MethodPost, "/games", `{"rule": "nope"}`, http.StatusBadRequest},
+		{"wall out of board", http.MethodPost, "/games", `{"walls": [{"x": 4, "y": 0}]}`, http.StatusBadRequest},
+		{"unknown id get", http.MethodGet, "/games/nope", "", http.StatusNotFound},
+		{"unknown id move", http.MethodPost, "/games/nope/moves", `{"dir": "Up"}`, http.StatusNotFound},
+		{"unknown id replay", http.MethodGet, "/games/nope/replay", "", http.StatusNotFound},
+		{"unknown id delete", http.MethodDelete, "/games/nope", "", http.StatusNotFound},
+		{"unknown path", http.MethodGet, "/boards", "", http.StatusNotFound},
+		{"unknown sub path", http.MethodGet, "/games/" + g.ID + "/undo", "", http.StatusNotFound},
+		{"unknown sub path post", http.MethodPost, "/games/" + g.ID + "/undo", "", http.StatusNotFound},
+		{"too deep", http.MethodGet, "/games/" + g.ID + "/moves/1", "", http.StatusNotFound},
+		{"bad method moves", http.MethodGet, "/games/" + g.ID + "/moves", "", http.StatusMethodNotAllowed},
+		{"bad method games", http.MethodGet, "/games", "", http.StatusMethodNotAllowed},
+		{"bad method", http.MethodPut, "/games/" + g.ID, "", http.StatusMethodNotAllowed},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			code, data := do(t, s, tt.method, tt.path, tt.body)
+			if code != tt.status {
+				t.Fatalf("status %d %s, want %d", code, data, tt.status)
+			}
+			var e errorResponse
+			decode(t, data, &e)
+			if !strings.HasPrefix(e.Error, "twenty48: ") {
+				t.Errorf("error = %q", e.Error)
+			}
+		})
+	}
+}
+
+func TestMaxGames(t *testing.T) {
+	s := New()
+	s.MaxGames = 2
+	a := create(t, s, "")
+	create(t, s, "")
+	if code, data := do(t, s, http.MethodPost, "/games", ""); code != http.StatusServiceUnavailable {
+		t.Fatalf("third game: status %d %s, want %d", code, data, http.StatusServiceUnavailable)
+	}
+	//删除一局后又可以开始
+	if code, _ := do(t, s, http.MethodDelete, "/games/"+a.ID, ""); code != http.StatusNoContent {
+		t.Fatalf("delete: status %d", code)
+	}
+	create(t, s, "")
+}
+
+func TestExpire(t *testing.T) {
+	s := New()
+	old := create(t, s, "")
+	fresh := create(t, s, "")
+	s.games[old.ID].updated = time.Now().Add(-2 * time.Hour)
+	if n := s.Expire(time.Hour); n != 1 {
+		t.Fatalf("Expire = %d, want 1", n)
+	}
+	if code, _ := do(t, s, http.MethodGet, "/games/"+old.ID, ""); code != http.StatusNotFound {
+		t.Errorf("idle game: status %d, want %d", code, http.StatusNotFound)
+	}
+	if code, _ := do(t, s, http.MethodGet, "/games/"+fresh.ID, ""); code != http.StatusOK {
+		t.Errorf("active game: status %d, want %d", code, http.StatusOK)
+	}
+	if n := s.Expire(time.Hour); n != 0 {
+		t.Errorf("second Expire = %d, want 0", n)
+	}
+}
+
+func TestExpireWhilePlaying(t *testing.T) {
+	//删除时同时有请求，用-race检查
+	s := New()
+	g := create(t, s, "")
+	var wg sync.WaitGroup
+	wg.Add(1)
+	go func() {
+		defer wg.Done()
+		for i := 0; i < 50; i++ {
+			do(t, s, http.MethodPost, "/games/"+g.ID+"/moves", `{"dir": "Left"}`)
+			do(t, s, http.MethodPost, "/games", "")
+		}
+	}()
+	for i := 0; i < 50; i++ {
+		s.Expire(time.Hour)
+	}
+	wg.Wait()
+	if n := s.Expire(0); n != 51 {
+		t.Errorf("Expire(0) = %d, want 51", n)
+	}
+}
+
+func TestConcurrentMoves(t *testing.T) {
+	s := New()
+	g := create(t, s, `{"seed": 7}`)
+	const workers, moves = 8, 25
+	var (
+		wg    sync.WaitGroup
+		mu    sync.Mutex
+		moved int
+	)
+	dirs := []string{"Up", "Right", "Down", "Left"}
+	for i := 0; i < workers; i++ {
+		wg.Add(1)
+		go func(i int) {
+			defer wg.Done()
+			for j := 0; j < moves; j++ {
+				d := dirs[(i+j)%len(dirs)]
+				code, data := do(t, s, http.MethodPost, "/games/"+g.ID+"/moves", `{"dir": "`+d+`"}`)
+				if code != http.StatusOK {
+					t.Errorf("move: status %d %s", code, data)
+					return
+				}
+				var m moveResponse
+				if err := json.Unmarshal(data, &m); err != nil {
+					t.Error(err)
+					return
+				}
+				if m.Moved {
+					mu.Lock()
+					moved++
+					mu.Unlock()
+				}
+			}
+		}(i)
+		//同时读取这局
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			do(t, s, http.MethodGet, "/games/"+g.ID, "")
+			do(t, s, http.MethodGet, "/games/"+g.ID+"/replay", "")
+		}()
+	}
+	wg.Wait()
+	code, data := do(t, s, http.MethodGet, "/games/"+g.ID, "")
+	if code != http.StatusOK {
+		t.Fatalf("get: status %d %s", code, data)
+	}
+	var got gameResponse
+	decode(t, data, &got)
+	//每次成功的移动都记入录像
+	if got.Moves != moved {
+		t.Errorf("moves = %d, want %d", got.Moves, moved)
+	}
+}